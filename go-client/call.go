@@ -0,0 +1,244 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+// compiledMessages are messages from the protobuf files compiled into the
+// client. The services in these files can be called without reflection.
+var compiledMessages = []proto.Message{
+	&pb.HelloRequest{},
+	&healthpb.HealthCheckRequest{},
+}
+
+// callCommand calls any unary or server-streaming method with a request
+// given as JSON, and prints the responses as JSON.
+type callCommand struct {
+	disco      string
+	addr       string
+	tls        bool
+	serverName string
+	caFile     string
+	certFile   string
+	keyFile    string
+	token      string
+	data       string
+	timeout    time.Duration
+	maxTime    time.Duration
+	qps        float64
+	burst      int
+	maxRetries uint
+}
+
+func init() {
+	RegisterCommand("call", func(flags *flag.FlagSet) Command {
+		cmd := new(callCommand)
+		flags.StringVar(&cmd.disco, "disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
+		flags.StringVar(&cmd.addr, "addr", ":10000", "Server address")
+		flags.BoolVar(&cmd.tls, "tls", false, "Enable TLS")
+		flags.StringVar(&cmd.serverName, "serverName", "", "Server to check the certificate")
+		flags.StringVar(&cmd.caFile, "caFile", "", "Certificate file in e.g. PEM format")
+		flags.StringVar(&cmd.certFile, "cert", "", "Client certificate file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.keyFile, "key", "", "Client key file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.token, "token", envString("TOKEN", ""), "Bearer token to authenticate with")
+		flags.StringVar(&cmd.data, "d", "", "Request as JSON, @file to read it from a file, or @- to read it from stdin (blank for an empty request)")
+		flags.DurationVar(&cmd.timeout, "timeout", 10*time.Second, "Timeout for unary calls")
+		flags.DurationVar(&cmd.maxTime, "max-time", 0, "Time after which to end server-streaming calls (0 to wait for the server to end them)")
+		flags.Float64Var(&cmd.qps, "qps", 0.0, "Rate limit for queries of seconds")
+		flags.IntVar(&cmd.burst, "burst", 0, "Rate limiter bursts")
+		flags.UintVar(&cmd.maxRetries, "retries", 5, "Number of retries when hitting rate limits")
+		return cmd
+	})
+}
+
+func (cmd *callCommand) Describe() string {
+	return "Call any unary or server-streaming method with a JSON request."
+}
+
+func (cmd *callCommand) Usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s call [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-caFile=...] [-cert=...] [-key=...] [-token=...] [-d=...] <method>\n", os.Args[0])
+}
+
+func (cmd *callCommand) Examples() []string {
+	return []string{
+		fmt.Sprintf(`%s call -token=alice-demo-token -d '{"name":"Oliver","age":42}' com.altf4.grpc.Example/Hello`, os.Args[0]),
+		fmt.Sprintf(`%s call -token=alice-demo-token -d @hello.json com.altf4.grpc.Example/Hello`, os.Args[0]),
+		fmt.Sprintf(`echo '{"interval":1000000000}' | %s call -token=alice-demo-token -d @- com.altf4.grpc.Example/Ticker`, os.Args[0]),
+		fmt.Sprintf(`%s call -d '{"service":"com.altf4.grpc.Example"}' grpc.health.v1.Health/Check`, os.Args[0]),
+	}
+}
+
+func (cmd *callCommand) Run(args []string) error {
+	if len(args) != 1 {
+		return UsageError("call requires the full name of a method, e.g. com.altf4.grpc.Example/Hello")
+	}
+	data, err := cmd.readData()
+	if err != nil {
+		return err
+	}
+
+	options := []ClientOption{
+		SetAddr(cmd.addr),
+		SetTLS(cmd.tls),
+		SetServerName(cmd.serverName),
+		SetCAFile(cmd.caFile),
+		SetClientCertificate(cmd.certFile, cmd.keyFile),
+		SetToken(cmd.token),
+		SetMaxRetries(cmd.maxRetries),
+	}
+	if cmd.qps > 0 && cmd.burst > 0 {
+		limiter := rate.NewLimiter(rate.Limit(cmd.qps), cmd.burst)
+		options = append(options, SetRateLimiter(limiter))
+	}
+	switch cmd.disco {
+	case "etcd":
+		etcdcli, err := clientv3.NewFromURL("http://localhost:2379")
+		if err != nil {
+			return err
+		}
+		options = append(options, SetEtcdClient(etcdcli))
+	}
+	client, err := NewClient(options...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	md, err := cmd.resolveMethod(client, args[0])
+	if err != nil {
+		return err
+	}
+	if md.IsClientStreaming() {
+		return errors.Errorf("%s is a client-streaming method, which call doesn't support", md.GetFullyQualifiedName())
+	}
+
+	req := dynamic.NewMessage(md.GetInputType())
+	if len(data) > 0 {
+		if err := req.UnmarshalJSON(data); err != nil {
+			return errors.Wrapf(err, "invalid request for %s", md.GetInputType().GetFullyQualifiedName())
+		}
+	}
+
+	if !md.IsServerStreaming() {
+		ctx, cancel := context.WithTimeout(context.Background(), cmd.timeout)
+		defer cancel()
+		res, err := client.Invoke(ctx, md, req)
+		if err != nil {
+			return wrapFieldViolations(err, "cannot call "+md.GetFullyQualifiedName())
+		}
+		return printJSON(res)
+	}
+
+	ctx := context.Background()
+	if cmd.maxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.maxTime)
+		defer cancel()
+	}
+	stream, err := client.InvokeServerStream(ctx, md, req)
+	if err != nil {
+		return errors.Wrap(err, "initiate stream")
+	}
+	for {
+		res, err := stream.RecvMsg()
+		if err == io.EOF || (err != nil && ctx.Err() == context.DeadlineExceeded) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "unexpected stream error")
+		}
+		if err := printJSON(res); err != nil {
+			return err
+		}
+	}
+}
+
+// readData returns the request as given in -d.
+func (cmd *callCommand) readData() ([]byte, error) {
+	switch {
+	case cmd.data == "@-":
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read request from stdin")
+		}
+		return data, nil
+	case strings.HasPrefix(cmd.data, "@"):
+		data, err := ioutil.ReadFile(cmd.data[1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read request")
+		}
+		return data, nil
+	default:
+		return []byte(cmd.data), nil
+	}
+}
+
+// resolveMethod returns the descriptor of method, e.g.
+// "com.altf4.grpc.Example/Hello". It looks in the protobuf files compiled
+// into the client first, and asks the server via reflection otherwise.
+func (cmd *callCommand) resolveMethod(client *Client, method string) (*desc.MethodDescriptor, error) {
+	name := symbolName(method)
+	i := strings.LastIndex(name, ".")
+	if i <= 0 {
+		return nil, errors.Errorf("invalid method %q; use e.g. com.altf4.grpc.Example/Hello", method)
+	}
+	serviceName, methodName := name[:i], name[i+1:]
+
+	var sd *desc.ServiceDescriptor
+	for _, m := range compiledMessages {
+		d, err := desc.LoadMessageDescriptorForMessage(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot load compiled descriptors")
+		}
+		if sd = d.GetFile().FindService(serviceName); sd != nil {
+			break
+		}
+	}
+	if sd == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cmd.timeout)
+		defer cancel()
+		rc := client.Reflection(ctx)
+		defer rc.Reset()
+
+		var err error
+		sd, err = rc.ResolveService(serviceName)
+		if err != nil {
+			return nil, reflectionError(err, "cannot resolve service "+serviceName)
+		}
+	}
+
+	md := sd.FindMethodByName(methodName)
+	if md == nil {
+		return nil, errors.Errorf("service %s has no method %s", serviceName, methodName)
+	}
+	return md, nil
+}
+
+// printJSON prints m as JSON.
+func printJSON(m proto.Message) error {
+	marshaler := &jsonpb.Marshaler{Indent: "  ", EmitDefaults: true}
+	s, err := marshaler.MarshalToString(m)
+	if err != nil {
+		return errors.Wrap(err, "cannot print response")
+	}
+	fmt.Println(s)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+// chatCommand executes the bidirectional streaming Chat RPC. It sends
+// every line of stdin to the room and prints the messages of the room.
+type chatCommand struct {
+	disco      string
+	addr       string
+	tls        bool
+	serverName string
+	caFile     string
+	certFile   string
+	keyFile    string
+	token      string
+	room       string
+}
+
+func init() {
+	RegisterCommand("chat", func(flags *flag.FlagSet) Command {
+		cmd := new(chatCommand)
+		flags.StringVar(&cmd.disco, "disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
+		flags.StringVar(&cmd.addr, "addr", ":10000", "Server address")
+		flags.BoolVar(&cmd.tls, "tls", false, "Enable TLS")
+		flags.StringVar(&cmd.serverName, "serverName", "", "Server to check the certificate")
+		flags.StringVar(&cmd.caFile, "caFile", "", "Certificate file in e.g. PEM format")
+		flags.StringVar(&cmd.certFile, "cert", "", "Client certificate file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.keyFile, "key", "", "Client key file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.token, "token", envString("TOKEN", ""), "Bearer token to authenticate with")
+		flags.StringVar(&cmd.room, "room", "lobby", "Chat room to join")
+		return cmd
+	})
+}
+
+func (cmd *chatCommand) Describe() string {
+	return "Run the bidirectional streaming Chat RPC call: send lines from stdin to a room and print its messages."
+}
+
+func (cmd *chatCommand) Usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s chat [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-cert=...] [-key=...] [-token=...] [-room=...]\n", os.Args[0])
+}
+
+func (cmd *chatCommand) Examples() []string {
+	return []string{
+		fmt.Sprintf("%s chat -token=alice-demo-token", os.Args[0]),
+		fmt.Sprintf("%s chat -token=bob-demo-token -room=grpc", os.Args[0]),
+		fmt.Sprintf("echo 'Hi all' | %s chat -token=alice-demo-token", os.Args[0]),
+	}
+}
+
+func (cmd *chatCommand) Run(args []string) error {
+	options := []ClientOption{
+		SetAddr(cmd.addr),
+		SetTLS(cmd.tls),
+		SetServerName(cmd.serverName),
+		SetCAFile(cmd.caFile),
+		SetClientCertificate(cmd.certFile, cmd.keyFile),
+		SetToken(cmd.token),
+	}
+	switch cmd.disco {
+	case "etcd":
+		etcdcli, err := clientv3.NewFromURL("http://localhost:2379")
+		if err != nil {
+			return err
+		}
+		options = append(options, SetEtcdClient(etcdcli))
+	}
+	client, err := NewClient(options...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Chat(ctx)
+	if err != nil {
+		return errors.Wrap(err, "initiate stream")
+	}
+	// If sending fails with io.EOF, the server ended the call and
+	// Recv below returns the reason
+	if err := stream.Send(&pb.ChatMessage{Room: cmd.room}); err != nil && err != io.EOF {
+		return errors.Wrap(err, "cannot join room")
+	}
+
+	// Send lines from stdin, and leave the room at the end of the input
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if err := stream.Send(&pb.ChatMessage{Text: scanner.Text()}); err != nil {
+				return
+			}
+		}
+		stream.CloseSend()
+	}()
+
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return wrapFieldViolations(err, "unexpected stream error")
+		}
+		ts := time.Unix(0, msg.Nanos).Format("15:04:05")
+		if msg.User == "" {
+			fmt.Printf("%s * %s\n", ts, msg.Text)
+		} else {
+			fmt.Printf("%s <%s> %s\n", ts, msg.User, msg.Text)
+		}
+	}
+}
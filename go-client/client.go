@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
 	"net"
@@ -10,8 +11,12 @@ import (
 
 	"github.com/coreos/etcd/clientv3"
 	etcdnaming "github.com/coreos/etcd/clientv3/naming"
-	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"github.com/golang/protobuf/proto"
+	grpcmw "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpcprom "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/olivere/grpc/lb/healthz"
 	"github.com/olivere/grpc/lb/static"
 	"github.com/pkg/errors"
@@ -20,7 +25,10 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/naming"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 
 	pb "github.com/olivere/grpc-demo/pb"
 )
@@ -28,12 +36,16 @@ import (
 type Client struct {
 	conn *grpc.ClientConn
 	c    pb.ExampleClient
+	h    healthpb.HealthClient
 
 	addr         string
 	healthchecks []string
 	tls          bool
 	serverName   string
 	caFile       string
+	certFile     string
+	keyFile      string
+	token        string
 	limiter      *rate.Limiter
 	maxRetries   uint
 	etcdcli      *clientv3.Client
@@ -78,23 +90,52 @@ func NewClient(options ...ClientOption) (*Client, error) {
 				return nil, errors.Wrap(err, "cannot split address into host and port")
 			}
 		}
-		creds := credentials.NewClientTLSFromCert(pool, sn)
+		tlscfg := &tls.Config{
+			ServerName: sn,
+			RootCAs:    pool,
+		}
+		if client.certFile != "" || client.keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(client.certFile, client.keyFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot load client certificate")
+			}
+			tlscfg.Certificates = []tls.Certificate{cert}
+		}
+		creds := credentials.NewTLS(tlscfg)
 		opts = append(opts, grpc.WithTransportCredentials(creds))
 	} else {
 		opts = append(opts, grpc.WithInsecure())
 	}
 
-	// Retries
-	retrycallopts := []grpcretry.CallOption{
-		grpcretry.WithMax(client.maxRetries),
-		grpcretry.WithCodes(codes.Unavailable, codes.ResourceExhausted),
+	// Authentication
+	if client.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(client.token)))
 	}
-	opts = append(opts, grpc.WithStreamInterceptor(grpcretry.StreamClientInterceptor(retrycallopts...)))
-	opts = append(opts, grpc.WithUnaryInterceptor(grpcretry.UnaryClientInterceptor(retrycallopts...)))
 
-	// Monitoring via Prometheus
-	opts = append(opts, grpc.WithUnaryInterceptor(grpcprom.UnaryClientInterceptor))
-	opts = append(opts, grpc.WithStreamInterceptor(grpcprom.StreamClientInterceptor))
+	// Keepalive pings, to notice broken connections, e.g. of Ticker streams.
+	// The server disconnects clients that ping more often than every 30s.
+	opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		Time:                time.Minute,
+		Timeout:             20 * time.Second,
+		PermitWithoutStream: true,
+	}))
+
+	// Retries, honoring the delay that the server asks for, and monitoring
+	// via Prometheus. gRPC only keeps the last interceptor passed, so we
+	// chain them. Retries come first, so that every attempt is monitored.
+	retry := retryPolicy{
+		maxRetries: client.maxRetries,
+		codes:      []codes.Code{codes.Unavailable, codes.ResourceExhausted},
+		backoff:    exponentialBackoff,
+	}
+	opts = append(opts, grpc.WithUnaryInterceptor(grpcmw.ChainUnaryClient(
+		retry.UnaryClientInterceptor,
+		grpcprom.UnaryClientInterceptor,
+	)))
+	opts = append(opts, grpc.WithStreamInterceptor(grpcmw.ChainStreamClient(
+		retry.StreamClientInterceptor,
+		grpcprom.StreamClientInterceptor,
+	)))
 
 	// Load balancing and service discovery
 	var err error
@@ -148,6 +189,7 @@ func NewClient(options ...ClientOption) (*Client, error) {
 	client.conn = conn
 
 	client.c = pb.NewExampleClient(client.conn)
+	client.h = healthpb.NewHealthClient(client.conn)
 
 	return client, nil
 }
@@ -215,6 +257,22 @@ func SetCAFile(caFile string) ClientOption {
 	}
 }
 
+// SetClientCertificate sets the certificate and key that the client
+// presents to servers that require mutual TLS.
+func SetClientCertificate(certFile, keyFile string) ClientOption {
+	return func(client *Client) {
+		client.certFile = certFile
+		client.keyFile = keyFile
+	}
+}
+
+// SetToken sets the bearer token to authenticate calls with.
+func SetToken(token string) ClientOption {
+	return func(client *Client) {
+		client.token = token
+	}
+}
+
 func SetRateLimiter(limiter *rate.Limiter) ClientOption {
 	return func(client *Client) {
 		client.limiter = limiter
@@ -256,3 +314,53 @@ func (c *Client) Ticker(ctx context.Context, in *pb.TickerRequest, opts ...grpc.
 	}
 	return c.c.Ticker(ctx, in, opts...)
 }
+
+func (c *Client) HelloMany(ctx context.Context, opts ...grpc.CallOption) (pb.Example_HelloManyClient, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.c.HelloMany(ctx, opts...)
+}
+
+func (c *Client) Chat(ctx context.Context, opts ...grpc.CallOption) (pb.Example_ChatClient, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.c.Chat(ctx, opts...)
+}
+
+// Invoke calls the unary method md with req, e.g. a dynamic message.
+func (c *Client) Invoke(ctx context.Context, md *desc.MethodDescriptor, req proto.Message, opts ...grpc.CallOption) (proto.Message, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return grpcdynamic.NewStub(c.conn).InvokeRpc(ctx, md, req, opts...)
+}
+
+// InvokeServerStream calls the server-streaming method md with req,
+// e.g. a dynamic message.
+func (c *Client) InvokeServerStream(ctx context.Context, md *desc.MethodDescriptor, req proto.Message, opts ...grpc.CallOption) (*grpcdynamic.ServerStream, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return grpcdynamic.NewStub(c.conn).InvokeRpcServerStream(ctx, md, req, opts...)
+}
+
+// Check asks the server for the serving status of a service.
+// Health checks are not subject to client-side rate limiting.
+func (c *Client) Check(ctx context.Context, in *healthpb.HealthCheckRequest, opts ...grpc.CallOption) (*healthpb.HealthCheckResponse, error) {
+	return c.h.Check(ctx, in, opts...)
+}
+
+// Watch streams the serving status of a service, sending an update
+// whenever it changes.
+func (c *Client) Watch(ctx context.Context, in *healthpb.HealthCheckRequest, opts ...grpc.CallOption) (healthpb.Health_WatchClient, error) {
+	return c.h.Watch(ctx, in, opts...)
+}
+
+// Reflection returns a client for the server reflection service, which
+// describes the services of the server. It is not subject to client-side
+// rate limiting. Call Reset on the returned client when done.
+func (c *Client) Reflection(ctx context.Context) *grpcreflect.Client {
+	return grpcreflect.NewClient(ctx, rpb.NewServerReflectionClient(c.conn))
+}
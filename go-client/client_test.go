@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+// rateLimitedServer rejects the first call of every method with
+// ResourceExhausted and a RetryInfo detail, like go-server's rate limiter.
+type rateLimitedServer struct {
+	pb.ExampleServer
+	retryDelay time.Duration
+	hellos     int32
+	tickers    int32
+}
+
+func (s *rateLimitedServer) rateLimitError() error {
+	st, err := status.New(codes.ResourceExhausted, "client exceeded rate limit").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(s.retryDelay),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return st.Err()
+}
+
+func (s *rateLimitedServer) Hello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloResponse, error) {
+	if atomic.AddInt32(&s.hellos, 1) == 1 {
+		return nil, s.rateLimitError()
+	}
+	return &pb.HelloResponse{Message: "Hello " + req.Name}, nil
+}
+
+func (s *rateLimitedServer) Ticker(req *pb.TickerRequest, stream pb.Example_TickerServer) error {
+	if atomic.AddInt32(&s.tickers, 1) == 1 {
+		return s.rateLimitError()
+	}
+	return stream.Send(&pb.TickerResponse{Tick: "tick", Sequence: 1})
+}
+
+// startTestServer starts a gRPC server with srv and returns a client
+// connected to it, and a function that stops both.
+func startTestServer(t *testing.T, srv pb.ExampleServer) (*Client, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterExampleServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+
+	client, err := NewClient(SetAddr(lis.Addr().String()))
+	if err != nil {
+		grpcServer.Stop()
+		t.Fatal(err)
+	}
+	return client, func() {
+		client.Close()
+		grpcServer.Stop()
+	}
+}
+
+func TestClientRetriesCallAfterRetryInfo(t *testing.T) {
+	srv := &rateLimitedServer{retryDelay: 200 * time.Millisecond}
+	client, stop := startTestServer(t, srv)
+	defer stop()
+
+	start := time.Now()
+	res, err := client.Hello(context.Background(), &pb.HelloRequest{Name: "Oliver"})
+	if err != nil {
+		t.Fatalf("expected the call to succeed after a retry, got %v", err)
+	}
+	if want, have := "Hello Oliver", res.Message; want != have {
+		t.Errorf("expected message %q, have %q", want, have)
+	}
+	if want, have := int32(2), atomic.LoadInt32(&srv.hellos); want != have {
+		t.Errorf("expected %d calls, have %d", want, have)
+	}
+	if elapsed := time.Since(start); elapsed < srv.retryDelay {
+		t.Errorf("expected the retry to wait for the RetryInfo delay of %v, it took %v", srv.retryDelay, elapsed)
+	}
+}
+
+func TestClientRetriesStreamAfterRetryInfo(t *testing.T) {
+	srv := &rateLimitedServer{retryDelay: 200 * time.Millisecond}
+	client, stop := startTestServer(t, srv)
+	defer stop()
+
+	start := time.Now()
+	stream, err := client.Ticker(context.Background(), &pb.TickerRequest{Interval: int64(time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("expected the stream to succeed after a retry, got %v", err)
+	}
+	if want, have := int64(1), res.Sequence; want != have {
+		t.Errorf("expected sequence %d, have %d", want, have)
+	}
+	if want, have := int32(2), atomic.LoadInt32(&srv.tickers); want != have {
+		t.Errorf("expected %d streams, have %d", want, have)
+	}
+	if elapsed := time.Since(start); elapsed < srv.retryDelay {
+		t.Errorf("expected the retry to wait for the RetryInfo delay of %v, it took %v", srv.retryDelay, elapsed)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoprint"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// describeCommand prints services, methods and messages of a server in
+// protobuf syntax, via server reflection.
+type describeCommand struct {
+	disco      string
+	addr       string
+	tls        bool
+	serverName string
+	caFile     string
+	certFile   string
+	keyFile    string
+	token      string
+	timeout    time.Duration
+}
+
+func init() {
+	RegisterCommand("describe", func(flags *flag.FlagSet) Command {
+		cmd := new(describeCommand)
+		flags.StringVar(&cmd.disco, "disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
+		flags.StringVar(&cmd.addr, "addr", ":10000", "Server address")
+		flags.BoolVar(&cmd.tls, "tls", false, "Enable TLS")
+		flags.StringVar(&cmd.serverName, "serverName", "", "Server to check the certificate")
+		flags.StringVar(&cmd.caFile, "caFile", "", "Certificate file in e.g. PEM format")
+		flags.StringVar(&cmd.certFile, "cert", "", "Client certificate file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.keyFile, "key", "", "Client key file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.token, "token", envString("TOKEN", ""), "Bearer token to authenticate with")
+		flags.DurationVar(&cmd.timeout, "timeout", 10*time.Second, "Timeout for call")
+		return cmd
+	})
+}
+
+func (cmd *describeCommand) Describe() string {
+	return "Describe services, methods and messages of the server, via server reflection."
+}
+
+func (cmd *describeCommand) Usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s describe [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-caFile=...] [-cert=...] [-key=...] [-token=...] [symbol...]\n", os.Args[0])
+}
+
+func (cmd *describeCommand) Examples() []string {
+	return []string{
+		fmt.Sprintf("%s describe -token=alice-demo-token", os.Args[0]),
+		fmt.Sprintf("%s describe -token=alice-demo-token com.altf4.grpc.Example.Hello", os.Args[0]),
+		fmt.Sprintf("%s describe -token=alice-demo-token com.altf4.grpc.HelloRequest", os.Args[0]),
+	}
+}
+
+func (cmd *describeCommand) Run(args []string) error {
+	options := []ClientOption{
+		SetAddr(cmd.addr),
+		SetTLS(cmd.tls),
+		SetServerName(cmd.serverName),
+		SetCAFile(cmd.caFile),
+		SetClientCertificate(cmd.certFile, cmd.keyFile),
+		SetToken(cmd.token),
+	}
+	switch cmd.disco {
+	case "etcd":
+		etcdcli, err := clientv3.NewFromURL("http://localhost:2379")
+		if err != nil {
+			return err
+		}
+		options = append(options, SetEtcdClient(etcdcli))
+	}
+	client, err := NewClient(options...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cmd.timeout)
+	defer cancel()
+	rc := client.Reflection(ctx)
+	defer rc.Reset()
+
+	// Describe all services by default
+	symbols := args
+	if len(symbols) == 0 {
+		symbols, err = rc.ListServices()
+		if err != nil {
+			return reflectionError(err, "cannot list services")
+		}
+		sort.Strings(symbols)
+	}
+
+	// Print the symbols, followed by the messages that their methods use
+	var descs []desc.Descriptor
+	seen := make(map[string]bool)
+	add := func(d desc.Descriptor) {
+		if !seen[d.GetFullyQualifiedName()] {
+			seen[d.GetFullyQualifiedName()] = true
+			descs = append(descs, d)
+		}
+	}
+	for _, symbol := range symbols {
+		d, err := resolveSymbol(rc, symbol)
+		if err != nil {
+			return err
+		}
+		add(d)
+	}
+	for i := 0; i < len(descs); i++ {
+		var methods []*desc.MethodDescriptor
+		switch d := descs[i].(type) {
+		case *desc.ServiceDescriptor:
+			methods = d.GetMethods()
+		case *desc.MethodDescriptor:
+			methods = []*desc.MethodDescriptor{d}
+		}
+		for _, md := range methods {
+			add(md.GetInputType())
+			add(md.GetOutputType())
+		}
+	}
+
+	printer := &protoprint.Printer{}
+	for _, d := range descs {
+		s, err := printer.PrintProtoToString(d)
+		if err != nil {
+			return errors.Wrapf(err, "cannot print %s", d.GetFullyQualifiedName())
+		}
+		fmt.Printf("%s is a %s:\n%s\n", d.GetFullyQualifiedName(), descriptorKind(d), s)
+	}
+	return nil
+}
+
+// resolveSymbol returns the descriptor of symbol, e.g. a service,
+// a method or a message, from the server.
+func resolveSymbol(rc *grpcreflect.Client, symbol string) (desc.Descriptor, error) {
+	name := symbolName(symbol)
+	fd, err := rc.FileContainingSymbol(name)
+	if err != nil {
+		return nil, reflectionError(err, "cannot resolve "+symbol)
+	}
+	d := fd.FindSymbol(name)
+	if d == nil {
+		return nil, errors.Errorf("cannot find %s in %s", symbol, fd.GetName())
+	}
+	return d, nil
+}
+
+// reflectionError wraps err with msg, or tells the user if the server
+// doesn't support reflection.
+func reflectionError(err error, msg string) error {
+	if status.Code(err) == codes.Unimplemented {
+		return errors.New("the server doesn't support reflection; start it with -reflection")
+	}
+	return errors.Wrap(err, msg)
+}
+
+// symbolName returns the fully-qualified name of a symbol. It also
+// accepts full method names, e.g. "/com.altf4.grpc.Example/Hello".
+func symbolName(symbol string) string {
+	return strings.Replace(strings.TrimPrefix(symbol, "/"), "/", ".", -1)
+}
+
+// descriptorKind returns what d describes, e.g. "service".
+func descriptorKind(d desc.Descriptor) string {
+	switch d.(type) {
+	case *desc.ServiceDescriptor:
+		return "service"
+	case *desc.MethodDescriptor:
+		return "method"
+	case *desc.MessageDescriptor:
+		return "message"
+	case *desc.FieldDescriptor:
+		return "field"
+	case *desc.EnumDescriptor:
+		return "enum"
+	case *desc.EnumValueDescriptor:
+		return "enum value"
+	default:
+		return "symbol"
+	}
+}
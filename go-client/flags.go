@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// stringsFlag is a flag that can be given more than once,
+// e.g. "-tag=a -tag=b".
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// mapFlag is a flag with key=value pairs that can be given more
+// than once, e.g. "-prop=a=1 -prop=b=2".
+type mapFlag map[string]string
+
+func (f mapFlag) String() string {
+	var pairs []string
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f mapFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("%q must be in the form key=value", value)
+	}
+	f[value[:i]] = value[i+1:]
+	return nil
+}
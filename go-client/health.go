@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthCommand probes the server via the gRPC health checking protocol.
+type healthCommand struct {
+	disco      string
+	addr       string
+	tls        bool
+	serverName string
+	caFile     string
+	certFile   string
+	keyFile    string
+	timeout    time.Duration
+	service    string
+	watch      bool
+}
+
+func init() {
+	RegisterCommand("health", func(flags *flag.FlagSet) Command {
+		cmd := new(healthCommand)
+		flags.StringVar(&cmd.disco, "disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
+		flags.StringVar(&cmd.addr, "addr", ":10000", "Server address")
+		flags.BoolVar(&cmd.tls, "tls", false, "Enable TLS")
+		flags.StringVar(&cmd.serverName, "serverName", "", "Server to check the certificate")
+		flags.StringVar(&cmd.caFile, "caFile", "", "Certificate file in e.g. PEM format")
+		flags.StringVar(&cmd.certFile, "cert", "", "Client certificate file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.keyFile, "key", "", "Client key file in e.g. PEM format, for servers that require client certificates")
+		flags.DurationVar(&cmd.timeout, "timeout", 10*time.Second, "Timeout for call")
+		flags.StringVar(&cmd.service, "service", "", "Service to check (blank for the server as a whole)")
+		flags.BoolVar(&cmd.watch, "watch", false, "Watch for changes of the serving status")
+		return cmd
+	})
+}
+
+func (cmd *healthCommand) Describe() string {
+	return "Check the serving status via the gRPC health checking protocol."
+}
+
+func (cmd *healthCommand) Usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s health [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-caFile=...] [-cert=...] [-key=...] [-service=...] [-watch]\n", os.Args[0])
+}
+
+func (cmd *healthCommand) Examples() []string {
+	return []string{
+		fmt.Sprintf("%s health -addr=localhost:10000", os.Args[0]),
+		fmt.Sprintf("%s health -service=com.altf4.grpc.Example", os.Args[0]),
+		fmt.Sprintf("%s health -watch", os.Args[0]),
+	}
+}
+
+func (cmd *healthCommand) Run(args []string) error {
+	options := []ClientOption{
+		SetAddr(cmd.addr),
+		SetTLS(cmd.tls),
+		SetServerName(cmd.serverName),
+		SetCAFile(cmd.caFile),
+		SetClientCertificate(cmd.certFile, cmd.keyFile),
+	}
+	switch cmd.disco {
+	case "etcd":
+		etcdcli, err := clientv3.NewFromURL("http://localhost:2379")
+		if err != nil {
+			return err
+		}
+		options = append(options, SetEtcdClient(etcdcli))
+	}
+	client, err := NewClient(options...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	req := &healthpb.HealthCheckRequest{Service: cmd.service}
+
+	if !cmd.watch {
+		ctx, cancel := context.WithTimeout(context.Background(), cmd.timeout)
+		defer cancel()
+		res, err := client.Check(ctx, req)
+		if err != nil {
+			return errors.Wrap(err, "cannot execute health check")
+		}
+		fmt.Println(res.Status)
+		if res.Status != healthpb.HealthCheckResponse_SERVING {
+			Exit(1)
+		}
+		return nil
+	}
+
+	stream, err := client.Watch(context.Background(), req)
+	if err != nil {
+		return errors.Wrap(err, "initiate stream")
+	}
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "unexpected stream error")
+		}
+		fmt.Printf("%s: %v\n", time.Now().Format(time.RFC3339), res.Status)
+	}
+}
@@ -1,19 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
-
-	"strings"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
 
 	"github.com/coreos/etcd/clientv3"
 	pb "github.com/olivere/grpc-demo/pb"
@@ -27,6 +28,17 @@ type helloCommand struct {
 	tls         bool
 	serverName  string
 	caFile      string
+	certFile    string
+	keyFile     string
+	token       string
+	name        string
+	age         int
+	nanos       int64
+	tags        stringsFlag
+	props       mapFlag
+	gender      string
+	online      bool
+	random      bool
 	timeout     time.Duration
 	qps         float64
 	burst       int
@@ -37,13 +49,24 @@ type helloCommand struct {
 
 func init() {
 	RegisterCommand("hello", func(flags *flag.FlagSet) Command {
-		cmd := new(helloCommand)
+		cmd := &helloCommand{props: make(mapFlag)}
 		flags.StringVar(&cmd.disco, "disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
 		flags.StringVar(&cmd.addr, "addr", ":10000", "Host and port to bind to")
 		flags.StringVar(&cmd.healthcheck, "healthcheck", "", "Comma-separated list of healthchecks for each gRPC endpoint")
 		flags.BoolVar(&cmd.tls, "tls", false, "Enable TLS")
 		flags.StringVar(&cmd.serverName, "serverName", "", "Server to check the certificate")
 		flags.StringVar(&cmd.caFile, "caFile", "", "Certificate file in e.g. PEM format")
+		flags.StringVar(&cmd.certFile, "cert", "", "Client certificate file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.keyFile, "key", "", "Client key file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.token, "token", envString("TOKEN", ""), "Bearer token to authenticate with")
+		flags.StringVar(&cmd.name, "name", "Oliver", "Name to send")
+		flags.IntVar(&cmd.age, "age", 42, "Age to send")
+		flags.Int64Var(&cmd.nanos, "nanos", -1, "Client time in nanoseconds since the epoch to send (-1 for the time of the call)")
+		flags.Var(&cmd.tags, "tag", "Tag to send (can be given more than once)")
+		flags.Var(cmd.props, "prop", "Property to send as key=value (can be given more than once)")
+		flags.StringVar(&cmd.gender, "gender", "UNSPECIFIED", "Gender to send (UNSPECIFIED, MALE or FEMALE)")
+		flags.BoolVar(&cmd.online, "online", false, "Send online as true")
+		flags.BoolVar(&cmd.random, "random", false, "Send a random name, age and gender with every request, instead of -name, -age and -gender")
 		flags.DurationVar(&cmd.timeout, "timeout", 10*time.Second, "Timeout for call")
 		flags.Float64Var(&cmd.qps, "qps", 0.0, "Rate limit for queries of seconds")
 		flags.IntVar(&cmd.burst, "burst", 0, "Rate limiter bursts")
@@ -59,7 +82,7 @@ func (cmd *helloCommand) Describe() string {
 }
 
 func (cmd *helloCommand) Usage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s hello [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-cert=...] [-key=...]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s hello [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-cert=...] [-key=...] [-token=...] [-name=...] [-age=...] [-nanos=...] [-tag=...] [-prop=key=value] [-gender=...] [-online] [-random]\n", os.Args[0])
 }
 
 func (cmd *helloCommand) Examples() []string {
@@ -67,16 +90,27 @@ func (cmd *helloCommand) Examples() []string {
 		fmt.Sprintf("%s hello", os.Args[0]),
 		fmt.Sprintf("%s hello -addr=localhost:10000", os.Args[0]),
 		fmt.Sprintf("%s hello -disco=etcd", os.Args[0]),
+		fmt.Sprintf("%s hello -token=alice-demo-token", os.Args[0]),
+		fmt.Sprintf("%s hello -name=Sandra -age=31 -gender=female -tag=admin -tag=beta -prop=team=search -online", os.Args[0]),
+		fmt.Sprintf("%s hello -random -parallel=10", os.Args[0]),
+		fmt.Sprintf("%s hello -tls -caFile=ca.pem -cert=alice.pem -key=alice.key", os.Args[0]),
 		fmt.Sprintf("%s hello -addr=localhost:10000,localhost:10001 -healthcheck=http://localhost:10000/healthz,http://localhost:10001/healthz", os.Args[0]),
 	}
 }
 
 func (cmd *helloCommand) Run(args []string) error {
+	gender, ok := pb.Gender_value[strings.ToUpper(cmd.gender)]
+	if !ok {
+		return UsageError(fmt.Sprintf("invalid gender %q", cmd.gender))
+	}
+
 	options := []ClientOption{
 		SetAddr(cmd.addr),
 		SetTLS(cmd.tls),
 		SetServerName(cmd.serverName),
 		SetCAFile(cmd.caFile),
+		SetClientCertificate(cmd.certFile, cmd.keyFile),
+		SetToken(cmd.token),
 		SetMaxRetries(cmd.maxRetries),
 	}
 	if cmd.qps > 0 && cmd.burst > 0 {
@@ -107,8 +141,6 @@ func (cmd *helloCommand) Run(args []string) error {
 
 	for {
 		ctx := context.Background()
-		// Set user
-		ctx = setUser(ctx, uuid.New().String())
 		// Add timeout
 		ctx, cancel := context.WithTimeout(ctx, cmd.timeout)
 		defer cancel()
@@ -118,16 +150,31 @@ func (cmd *helloCommand) Run(args []string) error {
 		for i := 0; i < cmd.parallel; i++ {
 			g.Go(func() error {
 				req := &pb.HelloRequest{
-					Name:   names[rand.Intn(len(names))],
-					Age:    int32(20 + rand.Intn(20)),
-					Nanos:  time.Now().UnixNano(),
-					Gender: randomGender(),
+					Name:       cmd.name,
+					Age:        int32(cmd.age),
+					Nanos:      cmd.nanos,
+					Tags:       cmd.tags,
+					Properties: cmd.props,
+					Gender:     pb.Gender(gender),
+					Online:     cmd.online,
+				}
+				if req.Nanos < 0 {
+					req.Nanos = time.Now().UnixNano()
+				}
+				if cmd.random {
+					req.Name = names[rand.Intn(len(names))]
+					req.Age = int32(20 + rand.Intn(20))
+					req.Gender = randomGender()
 				}
 				res, err := client.Hello(ctx, req)
 				if err != nil {
-					return errors.Wrap(err, "cannot execute Hello request")
+					return wrapFieldViolations(err, "cannot execute Hello request")
+				}
+				if res.Latency != 0 {
+					fmt.Printf("%s (latency: %v)\n", res.Message, time.Duration(res.Latency))
+				} else {
+					fmt.Println(res.Message)
 				}
-				fmt.Println(res.Message)
 				return nil
 			})
 		}
@@ -145,6 +192,25 @@ func (cmd *helloCommand) Run(args []string) error {
 	}
 }
 
+// wrapFieldViolations wraps err with msg. If err is a status with
+// a BadRequest detail, the field violations are added, one per line.
+func wrapFieldViolations(err error, msg string) error {
+	var buf bytes.Buffer
+	if st, ok := status.FromError(err); ok {
+		for _, detail := range st.Details() {
+			if br, ok := detail.(*errdetails.BadRequest); ok {
+				for _, v := range br.FieldViolations {
+					fmt.Fprintf(&buf, "\n  %s: %s", v.Field, v.Description)
+				}
+			}
+		}
+	}
+	if buf.Len() == 0 {
+		return errors.Wrap(err, msg)
+	}
+	return errors.Errorf("%s: %v%s", msg, err, buf.String())
+}
+
 func randomGender() pb.Gender {
 	switch rand.Int() % 3 {
 	case 0:
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+// helloManyCommand executes the client-streaming HelloMany RPC.
+type helloManyCommand struct {
+	disco      string
+	addr       string
+	tls        bool
+	serverName string
+	caFile     string
+	certFile   string
+	keyFile    string
+	token      string
+	n          int
+	interval   time.Duration
+	timeout    time.Duration
+	qps        float64
+	burst      int
+	maxRetries uint
+}
+
+func init() {
+	RegisterCommand("hellomany", func(flags *flag.FlagSet) Command {
+		cmd := new(helloManyCommand)
+		flags.StringVar(&cmd.disco, "disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
+		flags.StringVar(&cmd.addr, "addr", ":10000", "Server address")
+		flags.BoolVar(&cmd.tls, "tls", false, "Enable TLS")
+		flags.StringVar(&cmd.serverName, "serverName", "", "Server to check the certificate")
+		flags.StringVar(&cmd.caFile, "caFile", "", "Certificate file in e.g. PEM format")
+		flags.StringVar(&cmd.certFile, "cert", "", "Client certificate file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.keyFile, "key", "", "Client key file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.token, "token", envString("TOKEN", ""), "Bearer token to authenticate with")
+		flags.IntVar(&cmd.n, "n", 10, "Number of requests with random names to send if no names are given")
+		flags.DurationVar(&cmd.interval, "interval", 0, "Time to wait between sending requests")
+		flags.DurationVar(&cmd.timeout, "timeout", 10*time.Second, "Timeout for call")
+		flags.Float64Var(&cmd.qps, "qps", 0.0, "Rate limit for queries of seconds")
+		flags.IntVar(&cmd.burst, "burst", 0, "Rate limiter bursts")
+		flags.UintVar(&cmd.maxRetries, "retries", 5, "Number of retries when hitting rate limits")
+		return cmd
+	})
+}
+
+func (cmd *helloManyCommand) Describe() string {
+	return "Run the client-streaming HelloMany RPC call."
+}
+
+func (cmd *helloManyCommand) Usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s hellomany [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-cert=...] [-key=...] [-token=...] [-n=...] [-interval=...] [name...]\n", os.Args[0])
+}
+
+func (cmd *helloManyCommand) Examples() []string {
+	return []string{
+		fmt.Sprintf("%s hellomany -token=alice-demo-token Oliver Sandra Zoe", os.Args[0]),
+		fmt.Sprintf("%s hellomany -token=alice-demo-token -n=50 -interval=100ms", os.Args[0]),
+	}
+}
+
+func (cmd *helloManyCommand) Run(args []string) error {
+	batch := args
+	if len(batch) == 0 {
+		for i := 0; i < cmd.n; i++ {
+			batch = append(batch, names[rand.Intn(len(names))])
+		}
+	}
+
+	options := []ClientOption{
+		SetAddr(cmd.addr),
+		SetTLS(cmd.tls),
+		SetServerName(cmd.serverName),
+		SetCAFile(cmd.caFile),
+		SetClientCertificate(cmd.certFile, cmd.keyFile),
+		SetToken(cmd.token),
+		SetMaxRetries(cmd.maxRetries),
+	}
+	if cmd.qps > 0 && cmd.burst > 0 {
+		limiter := rate.NewLimiter(rate.Limit(cmd.qps), cmd.burst)
+		options = append(options, SetRateLimiter(limiter))
+	}
+	switch cmd.disco {
+	case "etcd":
+		etcdcli, err := clientv3.NewFromURL("http://localhost:2379")
+		if err != nil {
+			return err
+		}
+		options = append(options, SetEtcdClient(etcdcli))
+	}
+	client, err := NewClient(options...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cmd.timeout)
+	defer cancel()
+
+	stream, err := client.HelloMany(ctx)
+	if err != nil {
+		return errors.Wrap(err, "initiate stream")
+	}
+	for i, name := range batch {
+		if i > 0 && cmd.interval > 0 {
+			time.Sleep(cmd.interval)
+		}
+		err := stream.Send(&pb.HelloRequest{
+			Name:   name,
+			Age:    int32(20 + rand.Intn(20)),
+			Nanos:  time.Now().UnixNano(),
+			Gender: randomGender(),
+		})
+		if err != nil {
+			// The server ended the call; CloseAndRecv tells us why
+			break
+		}
+	}
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return wrapFieldViolations(err, "cannot execute HelloMany request")
+	}
+	for _, r := range res.Responses {
+		fmt.Printf("%s (latency: %v)\n", r.Message, time.Duration(r.Latency))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+// listCommand lists the services of a server, or the methods of
+// services, via server reflection.
+type listCommand struct {
+	disco      string
+	addr       string
+	tls        bool
+	serverName string
+	caFile     string
+	certFile   string
+	keyFile    string
+	token      string
+	timeout    time.Duration
+}
+
+func init() {
+	RegisterCommand("list", func(flags *flag.FlagSet) Command {
+		cmd := new(listCommand)
+		flags.StringVar(&cmd.disco, "disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
+		flags.StringVar(&cmd.addr, "addr", ":10000", "Server address")
+		flags.BoolVar(&cmd.tls, "tls", false, "Enable TLS")
+		flags.StringVar(&cmd.serverName, "serverName", "", "Server to check the certificate")
+		flags.StringVar(&cmd.caFile, "caFile", "", "Certificate file in e.g. PEM format")
+		flags.StringVar(&cmd.certFile, "cert", "", "Client certificate file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.keyFile, "key", "", "Client key file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.token, "token", envString("TOKEN", ""), "Bearer token to authenticate with")
+		flags.DurationVar(&cmd.timeout, "timeout", 10*time.Second, "Timeout for call")
+		return cmd
+	})
+}
+
+func (cmd *listCommand) Describe() string {
+	return "List the services of the server, or the methods of a service, via server reflection."
+}
+
+func (cmd *listCommand) Usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s list [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-caFile=...] [-cert=...] [-key=...] [-token=...] [service...]\n", os.Args[0])
+}
+
+func (cmd *listCommand) Examples() []string {
+	return []string{
+		fmt.Sprintf("%s list -token=alice-demo-token", os.Args[0]),
+		fmt.Sprintf("%s list -token=alice-demo-token com.altf4.grpc.Example", os.Args[0]),
+	}
+}
+
+func (cmd *listCommand) Run(args []string) error {
+	options := []ClientOption{
+		SetAddr(cmd.addr),
+		SetTLS(cmd.tls),
+		SetServerName(cmd.serverName),
+		SetCAFile(cmd.caFile),
+		SetClientCertificate(cmd.certFile, cmd.keyFile),
+		SetToken(cmd.token),
+	}
+	switch cmd.disco {
+	case "etcd":
+		etcdcli, err := clientv3.NewFromURL("http://localhost:2379")
+		if err != nil {
+			return err
+		}
+		options = append(options, SetEtcdClient(etcdcli))
+	}
+	client, err := NewClient(options...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cmd.timeout)
+	defer cancel()
+	rc := client.Reflection(ctx)
+	defer rc.Reset()
+
+	if len(args) == 0 {
+		services, err := rc.ListServices()
+		if err != nil {
+			return reflectionError(err, "cannot list services")
+		}
+		sort.Strings(services)
+		for _, service := range services {
+			fmt.Println(service)
+		}
+		return nil
+	}
+
+	for _, name := range args {
+		sd, err := rc.ResolveService(symbolName(name))
+		if err != nil {
+			return reflectionError(err, "cannot resolve service "+name)
+		}
+		for _, md := range sd.GetMethods() {
+			fmt.Println(md.GetFullyQualifiedName())
+		}
+	}
+	return nil
+}
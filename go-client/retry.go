@@ -0,0 +1,187 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryPolicy describes when and how often to retry a call.
+//
+// If the server tells us when to retry via a RetryInfo detail in the
+// error, we wait for that long. Otherwise we fall back to an
+// exponential backoff.
+type retryPolicy struct {
+	maxRetries uint
+	codes      []codes.Code
+	backoff    func(attempt uint) time.Duration
+}
+
+// exponentialBackoff waits 50ms, 100ms, 200ms, ... up to 5s.
+func exponentialBackoff(attempt uint) time.Duration {
+	d := 50 * time.Millisecond << attempt
+	if d <= 0 || d > 5*time.Second {
+		return 5 * time.Second
+	}
+	return d
+}
+
+// retryable returns true if err has one of the codes to retry.
+func (p retryPolicy) retryable(err error) bool {
+	code := status.Code(err)
+	for _, c := range p.codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the time to wait before retrying after err.
+func (p retryPolicy) delay(attempt uint, err error) time.Duration {
+	if st, ok := status.FromError(err); ok {
+		for _, detail := range st.Details() {
+			if ri, ok := detail.(*errdetails.RetryInfo); ok && ri.RetryDelay != nil {
+				if d, err := ptypes.Duration(ri.RetryDelay); err == nil {
+					return d
+				}
+			}
+		}
+	}
+	return p.backoff(attempt)
+}
+
+// wait waits before the next attempt. It returns false if we should
+// not retry, because err is not retryable, we ran out of attempts,
+// or the context is done.
+func (p retryPolicy) wait(ctx context.Context, attempt uint, err error) bool {
+	if attempt >= p.maxRetries || !p.retryable(err) {
+		return false
+	}
+	t := time.NewTimer(p.delay(attempt, err))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// UnaryClientInterceptor retries unary calls.
+func (p retryPolicy) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	for attempt := uint(0); ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil || !p.wait(ctx, attempt, err) {
+			return err
+		}
+	}
+}
+
+// StreamClientInterceptor retries server-side streams until the first
+// response has been received. After that, errors are passed to the caller.
+// Client-side and bidirectional streams are not retried.
+func (p retryPolicy) StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if desc.ClientStreams {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	var attempt uint
+	for {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err == nil {
+			return &retryingClientStream{
+				ClientStream: stream,
+				ctx:          ctx,
+				policy:       p,
+				attempt:      attempt,
+				open: func() (grpc.ClientStream, error) {
+					return streamer(ctx, desc, cc, method, opts...)
+				},
+			}, nil
+		}
+		if !p.wait(ctx, attempt, err) {
+			return nil, err
+		}
+		attempt++
+	}
+}
+
+// retryingClientStream re-opens a server-side stream if receiving
+// the first response fails.
+type retryingClientStream struct {
+	grpc.ClientStream
+
+	mu       sync.Mutex
+	ctx      context.Context
+	policy   retryPolicy
+	attempt  uint
+	open     func() (grpc.ClientStream, error)
+	request  interface{}
+	received bool
+}
+
+// SendMsg sends the request and remembers it for re-opening the stream.
+func (s *retryingClientStream) SendMsg(m interface{}) error {
+	s.mu.Lock()
+	s.request = m
+	stream := s.ClientStream
+	s.mu.Unlock()
+	return stream.SendMsg(m)
+}
+
+// RecvMsg receives a response, re-opening the stream if the first
+// response fails with a retryable error.
+func (s *retryingClientStream) RecvMsg(m interface{}) error {
+	for {
+		s.mu.Lock()
+		stream := s.ClientStream
+		s.mu.Unlock()
+
+		err := stream.RecvMsg(m)
+		if err == nil {
+			s.mu.Lock()
+			s.received = true
+			s.mu.Unlock()
+			return nil
+		}
+		if err == io.EOF || !s.reopen(err) {
+			return err
+		}
+	}
+}
+
+// reopen re-opens the stream after err. It returns false if we
+// should not retry.
+func (s *retryingClientStream) reopen(err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.received {
+		return false
+	}
+	for {
+		if !s.policy.wait(s.ctx, s.attempt, err) {
+			return false
+		}
+		s.attempt++
+		var stream grpc.ClientStream
+		stream, err = s.open()
+		if err != nil {
+			continue
+		}
+		if err = stream.SendMsg(s.request); err != nil {
+			continue
+		}
+		if err = stream.CloseSend(); err != nil {
+			continue
+		}
+		s.ClientStream = stream
+		return true
+	}
+}
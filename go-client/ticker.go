@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	"golang.org/x/sync/errgroup"
@@ -26,8 +25,14 @@ type tickerCommand struct {
 	tls         bool
 	serverName  string
 	caFile      string
+	certFile    string
+	keyFile     string
+	token       string
 	interval    time.Duration
 	timezone    string
+	resume      string
+	maxCount    int64
+	duration    time.Duration
 	qps         float64
 	burst       int
 	maxRetries  uint
@@ -44,8 +49,14 @@ func init() {
 		flags.BoolVar(&cmd.tls, "tls", false, "Enable TLS")
 		flags.StringVar(&cmd.serverName, "serverName", "", "Server to check the certificate")
 		flags.StringVar(&cmd.caFile, "caFile", "", "Certificate file in e.g. PEM format")
+		flags.StringVar(&cmd.certFile, "cert", "", "Client certificate file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.keyFile, "key", "", "Client key file in e.g. PEM format, for servers that require client certificates")
+		flags.StringVar(&cmd.token, "token", envString("TOKEN", ""), "Bearer token to authenticate with")
 		flags.DurationVar(&cmd.interval, "interval", 1*time.Second, "Time interval between ticker responses")
 		flags.StringVar(&cmd.timezone, "tz", time.Local.String(), "Timezone to pass to ticker")
+		flags.StringVar(&cmd.resume, "resume", "", "Resume token of the last tick received, to resume an interrupted stream")
+		flags.Int64Var(&cmd.maxCount, "count", 0, "Number of the tick after which to end the stream (0 for no limit)")
+		flags.DurationVar(&cmd.duration, "duration", 0, "Time after which to end the stream (0 for no limit)")
 		flags.Float64Var(&cmd.qps, "qps", 0.0, "Rate limit for queries of seconds")
 		flags.IntVar(&cmd.burst, "burst", 0, "Rate limiter bursts")
 		flags.UintVar(&cmd.maxRetries, "retries", 5, "Number of retries when hitting rate limits")
@@ -60,14 +71,17 @@ func (cmd *tickerCommand) Describe() string {
 }
 
 func (cmd *tickerCommand) Usage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s ticker [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-cert=...] [-key=...] [--ticker=...]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s ticker [-disco=...] [-addr=...] [-tls=...] [-serverName=...] [-cert=...] [-key=...] [-token=...] [-interval=...] [-tz=...] [-resume=...] [-count=...] [-duration=...]\n", os.Args[0])
 }
 
 func (cmd *tickerCommand) Examples() []string {
 	return []string{
 		fmt.Sprintf("%s ticker -addr=localhost:10000 -interval=5s", os.Args[0]),
 		fmt.Sprintf("%s ticker -interval=5s -tz=Europe/London", os.Args[0]),
+		fmt.Sprintf("%s ticker -interval=500ms -count=10", os.Args[0]),
+		fmt.Sprintf("%s ticker -interval=5s -resume=MTU...", os.Args[0]),
 		fmt.Sprintf("%s ticker -disco=etcd", os.Args[0]),
+		fmt.Sprintf("%s ticker -token=alice-demo-token", os.Args[0]),
 	}
 }
 
@@ -77,6 +91,8 @@ func (cmd *tickerCommand) Run(args []string) error {
 		SetTLS(cmd.tls),
 		SetServerName(cmd.serverName),
 		SetCAFile(cmd.caFile),
+		SetClientCertificate(cmd.certFile, cmd.keyFile),
+		SetToken(cmd.token),
 		SetMaxRetries(cmd.maxRetries),
 	}
 	if cmd.qps > 0 && cmd.burst > 0 {
@@ -107,18 +123,23 @@ func (cmd *tickerCommand) Run(args []string) error {
 
 	for {
 		ctx := context.Background()
-		// Set user
-		ctx = setUser(ctx, uuid.New().String())
 
 		g, ctx := errgroup.WithContext(ctx)
 
 		for i := 0; i < cmd.parallel; i++ {
 			g.Go(func() error {
 				req := &pb.TickerRequest{
-					Timezone: cmd.timezone,
-					Interval: cmd.interval.Nanoseconds(),
+					Timezone:    cmd.timezone,
+					Interval:    cmd.interval.Nanoseconds(),
+					ResumeToken: cmd.resume,
+					MaxCount:    cmd.maxCount,
 				}
-				stream, err := client.Ticker(ctx, req)
+				if cmd.duration > 0 {
+					req.End = time.Now().Add(cmd.duration).UnixNano()
+				}
+				stream, err := client.ResumableTicker(ctx, req, func(r TickerReconnect) {
+					fmt.Fprintf(os.Stderr, "Reconnecting in %v (attempt %d): %v\n", r.Delay, r.Attempt, r.Err)
+				})
 				if err != nil {
 					return errors.Wrap(err, "initiate stream")
 				}
@@ -128,9 +149,12 @@ func (cmd *tickerCommand) Run(args []string) error {
 						break
 					}
 					if err != nil {
-						return errors.Wrap(err, "unexpected stream error")
+						if token := stream.ResumeToken(); token != "" && token != cmd.resume {
+							return wrapFieldViolations(err, "unexpected stream error; resume with -resume="+token)
+						}
+						return wrapFieldViolations(err, "unexpected stream error")
 					}
-					fmt.Println(res.Tick)
+					fmt.Printf("%d %s\n", res.Sequence, res.Tick)
 				}
 				return nil
 			})
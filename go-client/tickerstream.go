@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+// TickerReconnect describes an attempt to re-establish an interrupted
+// Ticker stream.
+type TickerReconnect struct {
+	Attempt     uint          // number of the attempt since the last tick received, starting at 1
+	Err         error         // error that interrupted the stream, or that the previous attempt failed with
+	Delay       time.Duration // time to wait before the attempt
+	ResumeToken string        // resume token of the last tick received, if any
+}
+
+// TickerStream is a Ticker stream that re-establishes itself when it is
+// interrupted. See Client.ResumableTicker.
+type TickerStream struct {
+	c           *Client
+	ctx         context.Context
+	req         *pb.TickerRequest
+	opts        []grpc.CallOption
+	onReconnect func(TickerReconnect)
+	stream      pb.Example_TickerClient
+	attempt     uint
+}
+
+// ResumableTicker starts a Ticker stream that survives interruptions,
+// e.g. when the server shuts down or the connection breaks. If receiving
+// fails with Unavailable, the stream is re-established with exponential
+// backoff and resumes after the last tick received, until ctx is done.
+// onReconnect, if not nil, is called before every attempt.
+func (c *Client) ResumableTicker(ctx context.Context, in *pb.TickerRequest, onReconnect func(TickerReconnect), opts ...grpc.CallOption) (*TickerStream, error) {
+	req := proto.Clone(in).(*pb.TickerRequest)
+	stream, err := c.Ticker(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &TickerStream{
+		c:           c,
+		ctx:         ctx,
+		req:         req,
+		opts:        opts,
+		onReconnect: onReconnect,
+		stream:      stream,
+	}, nil
+}
+
+// Recv receives the next tick. It returns io.EOF when the server ends the
+// stream. Other errors than Unavailable are returned as is.
+func (s *TickerStream) Recv() (*pb.TickerResponse, error) {
+	for {
+		res, err := s.stream.Recv()
+		if err == nil {
+			s.req.ResumeToken = res.ResumeToken
+			s.attempt = 0
+			return res, nil
+		}
+		if status.Code(err) != codes.Unavailable {
+			return nil, err
+		}
+		if err := s.reconnect(err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ResumeToken returns the resume token of the last tick received.
+func (s *TickerStream) ResumeToken() string {
+	return s.req.ResumeToken
+}
+
+// reconnect re-establishes the stream after err. It returns an error if
+// it gives up, i.e. when ctx is done or on errors other than Unavailable.
+func (s *TickerStream) reconnect(err error) error {
+	for {
+		delay := exponentialBackoff(s.attempt)
+		s.attempt++
+		if s.onReconnect != nil {
+			s.onReconnect(TickerReconnect{
+				Attempt:     s.attempt,
+				Err:         err,
+				Delay:       delay,
+				ResumeToken: s.req.ResumeToken,
+			})
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-s.ctx.Done():
+			t.Stop()
+			return err
+		}
+
+		var stream pb.Example_TickerClient
+		stream, err = s.c.Ticker(s.ctx, s.req, s.opts...)
+		if err == nil {
+			s.stream = stream
+			return nil
+		}
+		if status.Code(err) != codes.Unavailable {
+			return err
+		}
+	}
+}
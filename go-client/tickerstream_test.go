@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+// droppingTickerServer sends ticksPerStream ticks on every Ticker stream,
+// continuing after the resume token, and then drops the stream with
+// Unavailable, until it has sent maxTicks ticks in total.
+type droppingTickerServer struct {
+	pb.ExampleServer
+	ticksPerStream int64
+	maxTicks       int64
+
+	mu     sync.Mutex
+	tokens []string // resume tokens the streams were started with
+}
+
+func (s *droppingTickerServer) Ticker(req *pb.TickerRequest, stream pb.Example_TickerServer) error {
+	s.mu.Lock()
+	s.tokens = append(s.tokens, req.ResumeToken)
+	s.mu.Unlock()
+
+	var seq int64
+	if req.ResumeToken != "" {
+		var err error
+		if seq, err = strconv.ParseInt(req.ResumeToken, 10, 64); err != nil {
+			return status.Error(codes.InvalidArgument, "invalid resume token")
+		}
+	}
+	for i := int64(0); i < s.ticksPerStream; i++ {
+		seq++
+		if seq > s.maxTicks {
+			return nil
+		}
+		err := stream.Send(&pb.TickerResponse{
+			Tick:        "tick",
+			Sequence:    seq,
+			ResumeToken: strconv.FormatInt(seq, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if seq >= s.maxTicks {
+		return nil
+	}
+	return status.Error(codes.Unavailable, "server is shutting down; please reconnect")
+}
+
+func TestResumableTickerResumesAfterUnavailable(t *testing.T) {
+	srv := &droppingTickerServer{ticksPerStream: 3, maxTicks: 7}
+	client, stop := startTestServer(t, srv)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var reconnects []TickerReconnect
+	stream, err := client.ResumableTicker(ctx, &pb.TickerRequest{Interval: int64(time.Second)}, func(r TickerReconnect) {
+		reconnects = append(reconnects, r)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sequences []int64
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected the stream to resume, got %v", err)
+		}
+		sequences = append(sequences, res.Sequence)
+	}
+
+	// Every tick exactly once, in order
+	if want, have := 7, len(sequences); want != have {
+		t.Fatalf("expected %d ticks, have %d: %v", want, have, sequences)
+	}
+	for i, seq := range sequences {
+		if want, have := int64(i+1), seq; want != have {
+			t.Fatalf("expected tick %d to have sequence %d, have %d: %v", i+1, want, have, sequences)
+		}
+	}
+	if want, have := "7", stream.ResumeToken(); want != have {
+		t.Errorf("expected resume token %q, have %q", want, have)
+	}
+
+	// Resumed after the last tick received of every stream
+	srv.mu.Lock()
+	tokens := fmt.Sprint(srv.tokens)
+	srv.mu.Unlock()
+	if want, have := "[ 3 6]", tokens; want != have {
+		t.Errorf("expected streams to start with resume tokens %s, have %s", want, have)
+	}
+
+	// onReconnect was called before every reconnect, with backoff
+	if want, have := 2, len(reconnects); want != have {
+		t.Fatalf("expected %d reconnects, have %d", want, have)
+	}
+	for i, r := range reconnects {
+		if want, have := uint(1), r.Attempt; want != have {
+			t.Errorf("reconnect %d: expected attempt %d, have %d", i+1, want, have)
+		}
+		if want, have := codes.Unavailable, status.Code(r.Err); want != have {
+			t.Errorf("reconnect %d: expected error with code %v, have %v", i+1, want, have)
+		}
+		if want, have := exponentialBackoff(0), r.Delay; want != have {
+			t.Errorf("reconnect %d: expected delay %v, have %v", i+1, want, have)
+		}
+	}
+	if want, have := "3", reconnects[0].ResumeToken; want != have {
+		t.Errorf("expected first reconnect with resume token %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AccessPolicy describes which methods a user may call. It is usually
+// loaded from a JSON file like this:
+//
+//	{
+//	  "roles": {
+//	    "hello": ["/com.altf4.grpc.Example/Hello"],
+//	    "ticker": ["/com.altf4.grpc.Example/Ticker"],
+//	    "admin": ["/com.altf4.grpc.Example/*"]
+//	  },
+//	  "users": {"alice": ["admin"]}
+//	}
+//
+// Roles map to the methods they grant access to, either by their full
+// name, e.g. "/com.altf4.grpc.Example/Hello", all methods of a service,
+// e.g. "/com.altf4.grpc.Example/*", or all methods via "*". The roles of
+// a user are the scopes of its identity, e.g. from its token, plus the
+// roles assigned to it in users. Everything else is denied.
+type AccessPolicy struct {
+	Roles map[string][]string `json:"roles"`
+	Users map[string][]string `json:"users,omitempty"`
+}
+
+// LoadAccessPolicy reads an access policy from a JSON file.
+func LoadAccessPolicy(filename string) (*AccessPolicy, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	policy := new(AccessPolicy)
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(policy); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", filename, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid access policy in %s: %v", filename, err)
+	}
+	return policy, nil
+}
+
+// Validate checks the policy for errors.
+func (p *AccessPolicy) Validate() error {
+	for role, methods := range p.Roles {
+		for _, method := range methods {
+			if method != "*" && !strings.HasPrefix(method, "/") {
+				return fmt.Errorf("role %s: method %q must be a full method name or *", role, method)
+			}
+		}
+	}
+	for user, roles := range p.Users {
+		for _, role := range roles {
+			if _, found := p.Roles[role]; !found {
+				return fmt.Errorf("user %s: unknown role %q", user, role)
+			}
+		}
+	}
+	return nil
+}
+
+// Allow returns true if id may call method. It also returns the
+// role that granted access.
+func (p *AccessPolicy) Allow(id *Identity, method string) (bool, string) {
+	for _, roles := range [][]string{id.Scopes, p.Users[id.Subject]} {
+		for _, role := range roles {
+			for _, pattern := range p.Roles[role] {
+				if matchMethod(pattern, method) {
+					return true, role
+				}
+			}
+		}
+	}
+	return false, ""
+}
+
+// matchMethod returns true if method matches pattern.
+func matchMethod(pattern, method string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, "/*"):
+		return strings.HasPrefix(method, pattern[:len(pattern)-1])
+	default:
+		return pattern == method
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+
+	"github.com/olivere/grpc-demo/go-server/health"
+)
+
+// adminHandler serves the admin API. All endpoints require HTTP basic
+// authentication against an htpasswd file, and every change is logged
+// together with the user who made it.
+type adminHandler struct {
+	logger log.Logger
+	health *health.Registry
+	users  htpasswd
+}
+
+// newAdminHandler creates the admin API, authenticating users
+// from the given htpasswd file.
+func newAdminHandler(logger log.Logger, registry *health.Registry, htpasswdFile string) (*adminHandler, error) {
+	users, err := loadHtpasswd(htpasswdFile)
+	if err != nil {
+		return nil, err
+	}
+	return &adminHandler{
+		logger: log.With(logger, "component", "admin"),
+		health: registry,
+		users:  users,
+	}, nil
+}
+
+// Register mounts the admin endpoints below /admin.
+func (h *adminHandler) Register(r *mux.Router) {
+	s := r.PathPrefix("/admin").Subrouter()
+	s.HandleFunc("/status", h.authenticated(h.getStatus)).Methods("GET")
+	s.HandleFunc("/liveness", h.authenticated(h.setCheck("admin.liveness", health.Liveness))).Methods("POST", "PUT")
+	s.HandleFunc("/readiness", h.authenticated(h.setCheck("admin.readiness", health.Readiness))).Methods("POST", "PUT")
+	s.HandleFunc("/drain", h.authenticated(h.setDrain)).Methods("POST", "PUT")
+}
+
+// authenticated only calls next if the request carries valid
+// basic authentication credentials.
+func (h *adminHandler) authenticated(next func(w http.ResponseWriter, r *http.Request, user string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || !h.users.Authenticate(user, password) {
+			h.logger.Log("msg", "authentication failed", "user", user, "remote", r.RemoteAddr, "url", r.RequestURI)
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next(w, r, user)
+	}
+}
+
+// adminStatus is returned by all admin endpoints.
+type adminStatus struct {
+	Alive    bool `json:"alive"`
+	Ready    bool `json:"ready"`
+	Draining bool `json:"draining"`
+}
+
+// getStatus returns the current liveness, readiness and drain state.
+func (h *adminHandler) getStatus(w http.ResponseWriter, r *http.Request, user string) {
+	h.writeStatus(w)
+}
+
+// setCheck returns a handler that sets the check with the given name
+// and kind. The body must be a JSON object like {"ok":false,"message":"..."}.
+func (h *adminHandler) setCheck(name string, kind health.Kind) func(w http.ResponseWriter, r *http.Request, user string) {
+	return func(w http.ResponseWriter, r *http.Request, user string) {
+		var req struct {
+			OK      *bool  `json:"ok"`
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OK == nil {
+			http.Error(w, `body must be a JSON object like {"ok":true}`, http.StatusBadRequest)
+			return
+		}
+		check := h.health.Register(name, kind)
+		if *req.OK {
+			check.Pass()
+		} else {
+			message := req.Message
+			if message == "" {
+				message = "set by " + user
+			}
+			check.Fail(message)
+		}
+		h.logger.Log("msg", "check changed", "user", user, "remote", r.RemoteAddr, "check", name, "ok", *req.OK, "message", req.Message)
+		h.writeStatus(w)
+	}
+}
+
+// setDrain enables or disables draining.
+// The body must be a JSON object like {"drain":true}.
+func (h *adminHandler) setDrain(w http.ResponseWriter, r *http.Request, user string) {
+	var req struct {
+		Drain *bool `json:"drain"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Drain == nil {
+		http.Error(w, `body must be a JSON object like {"drain":true}`, http.StatusBadRequest)
+		return
+	}
+	h.health.SetDraining(*req.Drain)
+	h.logger.Log("msg", "drain changed", "user", user, "remote", r.RemoteAddr, "drain", *req.Drain)
+	h.writeStatus(w)
+}
+
+func (h *adminHandler) writeStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adminStatus{
+		Alive:    h.health.Alive(),
+		Ready:    h.health.Ready(),
+		Draining: h.health.Draining(),
+	})
+}
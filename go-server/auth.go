@@ -1,46 +1,90 @@
 package main
 
 import (
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	"golang.org/x/net/context"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type contextKey uint
 
 const (
-	userKey contextKey = iota
+	identityKey contextKey = iota
+	authErrorKey
+	quotaKey
+	connAuthInfoKey
 )
 
-// authenticate takes the user from the gRPC metadata and
-// adds it into the context values, if available. Otherwise
-// an error with gRPC code Unauthenticated is returned.
-func authenticate(ctx context.Context) (context.Context, error) {
-	user, ok := extractUserFromMD(ctx)
-	if !ok {
-		return ctx, grpc.Errorf(codes.Unauthenticated, "request is not authenticated")
+// Identity is the verified identity of a caller.
+type Identity struct {
+	// Subject identifies the caller, e.g. a user name.
+	Subject string
+	// Scopes lists what the caller has been granted access to.
+	Scopes []string
+}
+
+// HasScope returns true if the identity has been granted scope.
+func (id *Identity) HasScope(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
 	}
-	return context.WithValue(ctx, userKey, user), nil
+	return false
+}
+
+// Authenticator verifies the credentials that a client passes with a call.
+type Authenticator interface {
+	// Authenticate verifies the credentials of the call in ctx, e.g. a
+	// token in its metadata or the certificate of its peer, and returns
+	// the identity of the caller. It returns an error if the call is not
+	// authenticated.
+	Authenticate(ctx context.Context) (*Identity, error)
 }
 
-// getUser returns the user previously added via authenticate.
-func getUser(ctx context.Context) (string, bool) {
-	if user, ok := ctx.Value(userKey).(string); ok && user != "" {
-		return user, true
+// authenticate verifies the call in ctx with a. Errors have gRPC code
+// Unauthenticated.
+func authenticate(ctx context.Context, a Authenticator) (*Identity, error) {
+	id, err := a.Authenticate(ctx)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
 	}
-	return "", false
+	return id, nil
 }
 
-// extractUserFromMD extracts the user from gRPC metadata.
-func extractUserFromMD(ctx context.Context) (string, bool) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return "", false
+// bearerToken returns the bearer token in the "authorization" metadata
+// of the call in ctx.
+func bearerToken(ctx context.Context) (string, error) {
+	return grpcauth.AuthFromMD(ctx, "bearer")
+}
+
+// authFunc returns a grpcauth.AuthFunc that adds the identity of the
+// caller into the context values. If the tap handler already verified
+// the call, its result is used. Otherwise the call is verified with a.
+func authFunc(a Authenticator) grpcauth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		if _, ok := getIdentity(ctx); ok {
+			return ctx, nil
+		}
+		if err, ok := ctx.Value(authErrorKey).(error); ok {
+			return ctx, err
+		}
+		id, err := authenticate(ctx, a)
+		if err != nil {
+			return ctx, err
+		}
+		return context.WithValue(ctx, identityKey, id), nil
 	}
-	values := md["user"]
-	if len(values) != 1 || values[0] == "" {
-		return "", false
+}
+
+// getIdentity returns the identity previously added via authFunc.
+func getIdentity(ctx context.Context) (*Identity, bool) {
+	if id, ok := ctx.Value(identityKey).(*Identity); ok && id != nil {
+		return id, true
 	}
-	return values[0], true
+	return nil, false
 }
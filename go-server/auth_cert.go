@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+
+	"github.com/soheilhy/cmux"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// certAuthenticator takes the identity of the caller from its client
+// certificate, which has been verified during the TLS handshake.
+type certAuthenticator struct{}
+
+// NewCertAuthenticator returns an Authenticator for mutual TLS. The
+// subject of the caller is the common name of its client certificate,
+// or the first e-mail address, DNS name or URI in its subject alternative
+// names if the common name is blank. The organizational units of the
+// certificate subject are used as scopes.
+//
+// The server must require and verify client certificates, and use
+// tlsInfoCredentials so that gRPC learns about them.
+func NewCertAuthenticator() Authenticator {
+	return certAuthenticator{}
+}
+
+// Authenticate implements the Authenticator interface.
+func (certAuthenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("unknown peer")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil, errors.New("no verified client certificate")
+	}
+	cert := info.State.VerifiedChains[0][0]
+	subject := certSubject(cert)
+	if subject == "" {
+		return nil, errors.New("client certificate has neither a common name nor a subject alternative name")
+	}
+	return &Identity{
+		Subject: subject,
+		Scopes:  cert.Subject.OrganizationalUnit,
+	}, nil
+}
+
+// certSubject returns the name of the owner of cert.
+func certSubject(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	}
+	return ""
+}
+
+// tlsInfoCredentials tells gRPC about the TLS connection of a peer.
+//
+// We terminate TLS in front of cmux, so gRPC only ever sees connections
+// that already passed the handshake. tlsInfoCredentials doesn't do a
+// handshake on its own. It only reports the state of the TLS connection
+// beneath, so that e.g. client certificates are available via
+// peer.FromContext.
+type tlsInfoCredentials struct{}
+
+// ClientHandshake is not supported.
+func (tlsInfoCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("tlsInfoCredentials can only be used on the server side")
+}
+
+// ServerHandshake returns the state of the TLS connection beneath conn.
+// Connections without TLS are passed on as they are.
+func (tlsInfoCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	c := conn
+	if mc, ok := c.(*cmux.MuxConn); ok {
+		c = mc.Conn
+	}
+	tc, ok := c.(*tls.Conn)
+	if !ok {
+		return conn, nil, nil
+	}
+	// This is a no-op if the handshake already happened while cmux
+	// was matching the connection
+	if err := tc.Handshake(); err != nil {
+		return nil, nil, err
+	}
+	return conn, credentials.TLSInfo{State: tc.ConnectionState()}, nil
+}
+
+// Info implements credentials.TransportCredentials.
+func (tlsInfoCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls"}
+}
+
+// Clone implements credentials.TransportCredentials.
+func (c tlsInfoCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+// OverrideServerName implements credentials.TransportCredentials.
+func (tlsInfoCredentials) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"golang.org/x/net/context"
+)
+
+// minHMACKeySize is the minimum size of an HS256 secret in bytes.
+const minHMACKeySize = 32
+
+// jwtClaims are the claims we expect in a JSON Web Token.
+type jwtClaims struct {
+	jwt.StandardClaims
+
+	// Scope is a space-separated list of scopes, as in OAuth 2.0.
+	Scope string `json:"scope,omitempty"`
+}
+
+// jwtAuthenticator verifies JSON Web Tokens signed with a single key.
+type jwtAuthenticator struct {
+	method   jwt.SigningMethod
+	key      interface{}
+	issuer   string
+	audience string
+}
+
+// NewJWTAuthenticator returns an Authenticator that accepts JSON Web
+// Tokens signed with the key in keyFile. If keyFile contains a PEM-encoded
+// RSA public key or certificate, tokens must be signed with RS256.
+// Otherwise the contents of keyFile are used as the secret for HS256.
+//
+// Tokens must have a subject and an expiry. The scopes of the caller
+// are taken from the space-separated "scope" claim. If issuer or audience
+// are not blank, tokens must have been issued by and for them.
+func NewJWTAuthenticator(keyFile, issuer, audience string) (Authenticator, error) {
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	a := &jwtAuthenticator{
+		issuer:   issuer,
+		audience: audience,
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		a.method = jwt.SigningMethodRS256
+		a.key, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse RSA public key in %s: %v", keyFile, err)
+		}
+	} else {
+		secret := bytes.TrimSpace(data)
+		if len(secret) < minHMACKeySize {
+			return nil, fmt.Errorf("secret in %s must have at least %d bytes", keyFile, minHMACKeySize)
+		}
+		a.method = jwt.SigningMethodHS256
+		a.key = secret
+	}
+	return a, nil
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *jwtAuthenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parser := &jwt.Parser{
+		// Only accept the algorithm of our key, so that e.g. an RSA
+		// public key can't be used as an HMAC secret
+		ValidMethods: []string{a.method.Alg()},
+	}
+	claims := new(jwtClaims)
+	_, err = parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return a.key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if claims.ExpiresAt == 0 {
+		return nil, errors.New("token has no expiry")
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return nil, errors.New("token has an invalid issuer")
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return nil, errors.New("token has an invalid audience")
+	}
+	return &Identity{
+		Subject: claims.Subject,
+		Scopes:  strings.Fields(claims.Scope),
+	}, nil
+}
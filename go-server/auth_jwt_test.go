@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testJWTIssuer   = "https://auth.example.com"
+	testJWTAudience = "grpc-demo"
+	testJWTSecret   = "0123456789abcdef0123456789abcdef"
+)
+
+// writeJWTKeys writes the public key of key and testJWTSecret into dir
+// and returns the names of both files.
+func writeJWTKeys(t *testing.T, dir string, key *rsa.PrivateKey) (rsaFile, hmacFile string) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaFile = filepath.Join(dir, "jwt.pem")
+	if err := ioutil.WriteFile(rsaFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hmacFile = filepath.Join(dir, "jwt.secret")
+	if err := ioutil.WriteFile(hmacFile, []byte(testJWTSecret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return rsaFile, hmacFile
+}
+
+// jwtContext returns the context of a call with token as bearer token.
+func jwtContext(token string) context.Context {
+	md := metadata.Pairs("authorization", "Bearer "+token)
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc-demo-jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaFile, hmacFile := writeJWTKeys(t, dir, key)
+	publicPEM, err := ioutil.ReadFile(rsaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := jwtClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "alice",
+			Issuer:    testJWTIssuer,
+			Audience:  testJWTAudience,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+		Scope: "hello ticker",
+	}
+	expired := valid
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "https://evil.example.com"
+	wrongAudience := valid
+	wrongAudience.Audience = "another-service"
+
+	tests := []struct {
+		name    string
+		keyFile string
+		method  jwt.SigningMethod
+		key     interface{}
+		claims  jwtClaims
+		ok      bool
+	}{
+		{"RS256", rsaFile, jwt.SigningMethodRS256, key, valid, true},
+		{"HS256", hmacFile, jwt.SigningMethodHS256, []byte(testJWTSecret), valid, true},
+		{"HS256 signed with the RSA public key", rsaFile, jwt.SigningMethodHS256, publicPEM, valid, false},
+		{"RS256 for an HS256 secret", hmacFile, jwt.SigningMethodRS256, key, valid, false},
+		{"none for RS256", rsaFile, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid, false},
+		{"none for HS256", hmacFile, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid, false},
+		{"expired", rsaFile, jwt.SigningMethodRS256, key, expired, false},
+		{"wrong issuer", rsaFile, jwt.SigningMethodRS256, key, wrongIssuer, false},
+		{"wrong audience", rsaFile, jwt.SigningMethodRS256, key, wrongAudience, false},
+	}
+	for _, tt := range tests {
+		a, err := NewJWTAuthenticator(tt.keyFile, testJWTIssuer, testJWTAudience)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		token, err := jwt.NewWithClaims(tt.method, tt.claims).SignedString(tt.key)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		id, err := a.Authenticate(jwtContext(token))
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("%s: expected token to be rejected", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected token to be accepted, got %v", tt.name, err)
+			continue
+		}
+		if want, have := "alice", id.Subject; want != have {
+			t.Errorf("%s: expected subject %q, have %q", tt.name, want, have)
+		}
+		if !id.HasScope("ticker") {
+			t.Errorf("%s: expected scope %q, have %v", tt.name, "ticker", id.Scopes)
+		}
+	}
+}
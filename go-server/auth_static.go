@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/net/context"
+)
+
+// staticToken is an API key in a tokens file.
+type staticToken struct {
+	Token   string   `json:"token"`
+	Subject string   `json:"subject"`
+	Scopes  []string `json:"scopes,omitempty"`
+}
+
+// staticAuthenticator verifies API keys against a fixed list.
+type staticAuthenticator struct {
+	// tokens is keyed by the SHA-256 hash of the token, so that
+	// looking up a token doesn't leak its contents via timing.
+	tokens map[[sha256.Size]byte]*Identity
+}
+
+// NewStaticAuthenticator returns an Authenticator that accepts the API
+// keys listed in a JSON file like this:
+//
+//	[
+//	  {"token": "alice-secret", "subject": "alice", "scopes": ["hello", "ticker"]},
+//	  {"token": "bob-secret", "subject": "bob", "scopes": ["hello"]}
+//	]
+func NewStaticAuthenticator(filename string) (Authenticator, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []staticToken
+	if err := json.NewDecoder(f).Decode(&list); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", filename, err)
+	}
+	a := &staticAuthenticator{
+		tokens: make(map[[sha256.Size]byte]*Identity),
+	}
+	for i, t := range list {
+		if t.Token == "" {
+			return nil, fmt.Errorf("token %d in %s: missing token", i, filename)
+		}
+		if t.Subject == "" {
+			return nil, fmt.Errorf("token %d in %s: missing subject", i, filename)
+		}
+		hash := sha256.Sum256([]byte(t.Token))
+		if _, found := a.tokens[hash]; found {
+			return nil, fmt.Errorf("token %d in %s: duplicate token", i, filename)
+		}
+		a.tokens[hash] = &Identity{Subject: t.Subject, Scopes: t.Scopes}
+	}
+	return a, nil
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *staticAuthenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, found := a.tokens[sha256.Sum256([]byte(token))]
+	if !found {
+		return nil, errors.New("invalid token")
+	}
+	return id, nil
+}
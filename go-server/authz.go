@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authorizer decides whether an authenticated user may call a method,
+// based on an AccessPolicy. Every decision is logged.
+type Authorizer struct {
+	logger log.Logger
+
+	mu     sync.RWMutex
+	policy *AccessPolicy
+}
+
+// NewAuthorizer creates a new Authorizer. If policy is nil, every
+// authenticated user may call every method.
+func NewAuthorizer(logger log.Logger, policy *AccessPolicy) *Authorizer {
+	return &Authorizer{
+		logger: log.With(logger, "component", "authz"),
+		policy: policy,
+	}
+}
+
+// SetPolicy replaces the access policy.
+func (a *Authorizer) SetPolicy(policy *AccessPolicy) {
+	a.mu.Lock()
+	a.policy = policy
+	a.mu.Unlock()
+}
+
+// authorize returns an error with gRPC code PermissionDenied if the
+// caller in ctx must not call method.
+func (a *Authorizer) authorize(ctx context.Context, method string) error {
+	id, ok := getIdentity(ctx)
+	if !ok {
+		a.logger.Log("method", method, "allowed", false, "reason", "not authenticated")
+		return status.Error(codes.Unauthenticated, "request is not authenticated")
+	}
+
+	a.mu.RLock()
+	policy := a.policy
+	a.mu.RUnlock()
+
+	if policy == nil {
+		a.logger.Log("method", method, "user", id.Subject, "allowed", true, "reason", "no access policy")
+		return nil
+	}
+	if allowed, role := policy.Allow(id, method); allowed {
+		a.logger.Log("method", method, "user", id.Subject, "allowed", true, "role", role)
+		return nil
+	}
+	a.logger.Log("method", method, "user", id.Subject, "allowed", false, "reason", "no role grants access", "scopes", strings.Join(id.Scopes, " "))
+	return status.Errorf(codes.PermissionDenied, "user %s must not call %s", id.Subject, method)
+}
+
+// UnaryServerInterceptor rejects unary calls that the access policy
+// doesn't allow. It must run after authentication. Services that skip
+// authentication via grpcauth.ServiceAuthFuncOverride skip authorization, too.
+func (a *Authorizer) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if _, ok := info.Server.(grpcauth.ServiceAuthFuncOverride); ok {
+		return handler(ctx, req)
+	}
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor rejects streams that the access policy
+// doesn't allow. It must run after authentication. Services that skip
+// authentication via grpcauth.ServiceAuthFuncOverride skip authorization, too.
+func (a *Authorizer) StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, ok := srv.(grpcauth.ServiceAuthFuncOverride); ok {
+		return handler(srv, stream)
+	}
+	if err := a.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// certWatcher keeps the server certificate up to date without a restart.
+// It reloads the key pair when the files change or when asked to via
+// Reload, and hands out the current one to new TLS connections via
+// GetCertificate. Existing connections, e.g. long-running streams,
+// keep the certificate they were established with.
+//
+// A new key pair is only used if it is valid, i.e. the key matches the
+// certificate and the certificate is within its validity period.
+// Otherwise the previous one is kept.
+//
+// certWatcher implements prometheus.Collector and reports the expiry of
+// the current certificate and the number of reloads.
+type certWatcher struct {
+	reloads  uint64 // number of successful reloads
+	failures uint64 // number of failed reloads
+
+	logger   log.Logger
+	certFile string
+	keyFile  string
+	now      func() time.Time
+
+	mu    sync.RWMutex
+	cert  *tls.Certificate
+	stamp string // size and modification time of the files last loaded
+
+	expiryDesc *prometheus.Desc
+	reloadDesc *prometheus.Desc
+}
+
+// newCertWatcher loads the key pair in certFile and keyFile.
+func newCertWatcher(logger log.Logger, certFile, keyFile string) (*certWatcher, error) {
+	w := &certWatcher{
+		logger:   log.With(logger, "component", "certs"),
+		certFile: certFile,
+		keyFile:  keyFile,
+		now:      time.Now,
+		expiryDesc: prometheus.NewDesc(
+			"grpc_demo_tls_certificate_expiry_timestamp_seconds",
+			"Time when the current server certificate expires, in seconds since the epoch.",
+			nil, nil,
+		),
+		reloadDesc: prometheus.NewDesc(
+			"grpc_demo_tls_certificate_reloads_total",
+			"Number of attempts to reload the server certificate, by result.",
+			[]string{"result"}, nil,
+		),
+	}
+	stamp, err := w.fileStamp()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.cert = cert
+	w.stamp = stamp
+	return w, nil
+}
+
+// GetCertificate returns the current certificate. It is meant to be used
+// as tls.Config.GetCertificate.
+func (w *certWatcher) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cert, nil
+}
+
+// Reload loads the key pair from the files and uses it for new
+// connections, if it is valid.
+func (w *certWatcher) Reload() error {
+	stamp, err := w.fileStamp()
+	if err != nil {
+		atomic.AddUint64(&w.failures, 1)
+		return err
+	}
+	return w.reload(stamp)
+}
+
+// Watch checks the files for changes every interval, and reloads the
+// key pair if they changed. It never returns.
+func (w *certWatcher) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		stamp, err := w.fileStamp()
+		if err != nil {
+			w.logger.Log("msg", "Cannot check certificate for changes", "err", err)
+			continue
+		}
+		w.mu.RLock()
+		changed := stamp != w.stamp
+		w.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := w.reload(stamp); err != nil {
+			w.logger.Log("msg", "Cannot reload certificate; keeping the previous one", "cert", w.certFile, "key", w.keyFile, "err", err)
+		}
+	}
+}
+
+// reload loads and swaps in the key pair, remembering stamp. If the files
+// are updated one after the other, the first attempt may fail because the
+// certificate doesn't match the key yet. We remember stamp anyway, so that
+// Watch tries again once the second file changes, but not before.
+func (w *certWatcher) reload(stamp string) error {
+	cert, err := w.load()
+
+	w.mu.Lock()
+	w.stamp = stamp
+	if err == nil {
+		w.cert = cert
+	}
+	w.mu.Unlock()
+
+	if err != nil {
+		atomic.AddUint64(&w.failures, 1)
+		return err
+	}
+	atomic.AddUint64(&w.reloads, 1)
+	w.logger.Log("msg", "Certificate reloaded", "cert", w.certFile, "subject", cert.Leaf.Subject.CommonName, "notAfter", cert.Leaf.NotAfter)
+	return nil
+}
+
+// load loads and validates the key pair.
+func (w *certWatcher) load() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(w.certFile, w.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	now := w.now()
+	if now.Before(cert.Leaf.NotBefore) {
+		return nil, fmt.Errorf("certificate is not valid before %v", cert.Leaf.NotBefore)
+	}
+	if now.After(cert.Leaf.NotAfter) {
+		return nil, fmt.Errorf("certificate expired at %v", cert.Leaf.NotAfter)
+	}
+	return &cert, nil
+}
+
+// fileStamp returns the size and modification time of the files,
+// which change when the files are changed.
+func (w *certWatcher) fileStamp() (string, error) {
+	var stamp string
+	for _, filename := range []string{w.certFile, w.keyFile} {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return "", err
+		}
+		stamp += fmt.Sprintf("%d:%d/", fi.Size(), fi.ModTime().UnixNano())
+	}
+	return stamp, nil
+}
+
+// Describe implements prometheus.Collector.
+func (w *certWatcher) Describe(ch chan<- *prometheus.Desc) {
+	ch <- w.expiryDesc
+	ch <- w.reloadDesc
+}
+
+// Collect implements prometheus.Collector.
+func (w *certWatcher) Collect(ch chan<- prometheus.Metric) {
+	w.mu.RLock()
+	notAfter := w.cert.Leaf.NotAfter
+	w.mu.RUnlock()
+	ch <- prometheus.MustNewConstMetric(w.expiryDesc, prometheus.GaugeValue, float64(notAfter.Unix()))
+	ch <- prometheus.MustNewConstMetric(w.reloadDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&w.reloads)), "success")
+	ch <- prometheus.MustNewConstMetric(w.reloadDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&w.failures)), "failure")
+}
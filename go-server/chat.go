@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+// chatBufferSize is the number of messages buffered for a chat member.
+// Members that fall further behind are removed from their room, so slow
+// clients cannot hold up the others.
+const chatBufferSize = 100
+
+// chatRooms keeps track of the members of chat rooms and fans out
+// messages to them.
+type chatRooms struct {
+	mu    sync.Mutex
+	rooms map[string]map[*chatMember]struct{}
+}
+
+// chatMember is a user in a chat room. It receives the messages of the
+// room in out. gone is closed when the member is removed from the room
+// because it didn't keep up.
+type chatMember struct {
+	room string
+	user string
+	out  chan *pb.ChatMessage
+	gone chan struct{}
+}
+
+func newChatRooms() *chatRooms {
+	return &chatRooms{
+		rooms: make(map[string]map[*chatMember]struct{}),
+	}
+}
+
+// join adds user to room and tells the other members about it.
+func (r *chatRooms) join(room, user string) *chatMember {
+	m := &chatMember{
+		room: room,
+		user: user,
+		out:  make(chan *pb.ChatMessage, chatBufferSize),
+		gone: make(chan struct{}),
+	}
+	r.mu.Lock()
+	members, found := r.rooms[room]
+	if !found {
+		members = make(map[*chatMember]struct{})
+		r.rooms[room] = members
+	}
+	members[m] = struct{}{}
+	r.mu.Unlock()
+
+	r.send(room, "", user+" joined")
+	return m
+}
+
+// leave removes m from its room, if it is still in there, and tells the
+// other members about it.
+func (r *chatRooms) leave(m *chatMember) {
+	r.mu.Lock()
+	r.remove(m)
+	r.mu.Unlock()
+
+	r.send(m.room, "", m.user+" left")
+}
+
+// send sends text from user to all members of room. Messages from the
+// server itself have no user.
+func (r *chatRooms) send(room, user, text string) {
+	msg := &pb.ChatMessage{
+		Room:  room,
+		User:  user,
+		Text:  text,
+		Nanos: time.Now().UnixNano(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for m := range r.rooms[room] {
+		select {
+		case m.out <- msg:
+		default:
+			// The member didn't keep up
+			r.remove(m)
+			close(m.gone)
+		}
+	}
+}
+
+// remove removes m from its room, and the room if it is empty.
+// The caller must hold r.mu.
+func (r *chatRooms) remove(m *chatMember) {
+	members := r.rooms[m.room]
+	delete(members, m)
+	if len(members) == 0 {
+		delete(r.rooms, m.room)
+	}
+}
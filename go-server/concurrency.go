@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConcurrencyLimiter limits the number of unary calls and streams a user
+// may have in flight at the same time. The rate limiter only limits how
+// fast calls are started, so without it, a user could keep an unlimited
+// number of long-lived streams, e.g. Ticker, open at once.
+//
+// ConcurrencyLimiter implements prometheus.Collector and reports the
+// number of calls and streams in flight per user, and the number of
+// rejected calls and streams.
+type ConcurrencyLimiter struct {
+	maxCalls   int
+	maxStreams int
+
+	mu       sync.Mutex
+	inflight map[string]*inflight // by user
+
+	rejectedCalls   uint64
+	rejectedStreams uint64
+
+	inflightDesc *prometheus.Desc
+	rejectedDesc *prometheus.Desc
+}
+
+// inflight is the number of calls and streams a user has in flight.
+type inflight struct {
+	calls   int
+	streams int
+}
+
+// NewConcurrencyLimiter creates a limiter that allows every user at most
+// maxCalls unary calls and maxStreams streams at the same time. A limit
+// of zero disables it.
+func NewConcurrencyLimiter(maxCalls, maxStreams int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		maxCalls:   maxCalls,
+		maxStreams: maxStreams,
+		inflight:   make(map[string]*inflight),
+		inflightDesc: prometheus.NewDesc(
+			"grpc_demo_user_inflight",
+			"Number of unary calls and streams in flight, by user and type.",
+			[]string{"user", "type"}, nil,
+		),
+		rejectedDesc: prometheus.NewDesc(
+			"grpc_demo_user_inflight_rejected_total",
+			"Number of unary calls and streams rejected because the user had too many in flight, by type.",
+			[]string{"type"}, nil,
+		),
+	}
+}
+
+// acquireCall counts a unary call of user. It returns false if the user
+// has too many calls in flight already.
+func (l *ConcurrencyLimiter) acquireCall(user string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.inflight[user]
+	if n == nil {
+		n = new(inflight)
+		l.inflight[user] = n
+	}
+	if l.maxCalls > 0 && n.calls >= l.maxCalls {
+		atomic.AddUint64(&l.rejectedCalls, 1)
+		return false
+	}
+	n.calls++
+	return true
+}
+
+// releaseCall marks a unary call of user as finished.
+func (l *ConcurrencyLimiter) releaseCall(user string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n := l.inflight[user]; n != nil {
+		n.calls--
+		l.removeIdleLocked(user, n)
+	}
+}
+
+// acquireStream counts a stream of user. It returns false if the user
+// has too many streams open already.
+func (l *ConcurrencyLimiter) acquireStream(user string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.inflight[user]
+	if n == nil {
+		n = new(inflight)
+		l.inflight[user] = n
+	}
+	if l.maxStreams > 0 && n.streams >= l.maxStreams {
+		atomic.AddUint64(&l.rejectedStreams, 1)
+		return false
+	}
+	n.streams++
+	return true
+}
+
+// releaseStream marks a stream of user as finished.
+func (l *ConcurrencyLimiter) releaseStream(user string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n := l.inflight[user]; n != nil {
+		n.streams--
+		l.removeIdleLocked(user, n)
+	}
+}
+
+// removeIdleLocked forgets about users without calls or streams in
+// flight, so that we only keep and report active users.
+// The caller must hold l.mu.
+func (l *ConcurrencyLimiter) removeIdleLocked(user string, n *inflight) {
+	if n.calls <= 0 && n.streams <= 0 {
+		delete(l.inflight, user)
+	}
+}
+
+// UnaryServerInterceptor rejects unary calls with ResourceExhausted if
+// the user has too many calls in flight. It must run after authentication.
+// Calls without an identity, e.g. health checks, are not limited.
+func (l *ConcurrencyLimiter) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id, ok := getIdentity(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+	if !l.acquireCall(id.Subject) {
+		return nil, concurrencyLimitError(id.Subject, "calls", l.maxCalls)
+	}
+	defer l.releaseCall(id.Subject)
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor rejects streams with ResourceExhausted if the
+// user has too many streams open. It must run after authentication.
+// Streams without an identity, e.g. health checks, are not limited.
+func (l *ConcurrencyLimiter) StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id, ok := getIdentity(stream.Context())
+	if !ok {
+		return handler(srv, stream)
+	}
+	if !l.acquireStream(id.Subject) {
+		return concurrencyLimitError(id.Subject, "streams", l.maxStreams)
+	}
+	defer l.releaseStream(id.Subject)
+	return handler(srv, stream)
+}
+
+// Describe implements prometheus.Collector.
+func (l *ConcurrencyLimiter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- l.inflightDesc
+	ch <- l.rejectedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (l *ConcurrencyLimiter) Collect(ch chan<- prometheus.Metric) {
+	// Copy the counts, so that we don't block calls while sending
+	type userInflight struct {
+		user string
+		inflight
+	}
+	l.mu.Lock()
+	counts := make([]userInflight, 0, len(l.inflight))
+	for user, n := range l.inflight {
+		counts = append(counts, userInflight{user: user, inflight: *n})
+	}
+	l.mu.Unlock()
+	for _, n := range counts {
+		ch <- prometheus.MustNewConstMetric(l.inflightDesc, prometheus.GaugeValue, float64(n.calls), n.user, "unary")
+		ch <- prometheus.MustNewConstMetric(l.inflightDesc, prometheus.GaugeValue, float64(n.streams), n.user, "stream")
+	}
+	ch <- prometheus.MustNewConstMetric(l.rejectedDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&l.rejectedCalls)), "unary")
+	ch <- prometheus.MustNewConstMetric(l.rejectedDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&l.rejectedStreams)), "stream")
+}
+
+// concurrencyLimitError returns a ResourceExhausted error that tells the
+// client which quota it exceeded via a QuotaFailure detail. There is no
+// RetryInfo, as we cannot know when the user's other calls finish.
+func concurrencyLimitError(user, what string, limit int) error {
+	st := status.Newf(codes.ResourceExhausted, "too many concurrent %s, at most %d per user", what, limit)
+	details, err := st.WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     "user:" + user,
+			Description: fmt.Sprintf("at most %d concurrent %s per user", limit, what),
+		}},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return details.Err()
+}
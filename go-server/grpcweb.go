@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/tap"
+)
+
+// grpcWebHandler serves gRPC-Web requests from browsers, i.e. requests
+// with a content type of application/grpc-web or application/grpc-web-text,
+// as well as their CORS preflight requests. Requests are translated to
+// native gRPC and passed to the gRPC server, so they go through the same
+// interceptors as native gRPC calls, including server streaming.
+type grpcWebHandler struct {
+	*grpcweb.WrappedGrpcServer
+}
+
+// newGRPCWebHandler creates a new gRPC-Web handler for grpcServer.
+// tapHandle is the tap handler of grpcServer, which gRPC doesn't run for
+// requests via HTTP/1.1. Cross-origin requests are allowed from origins,
+// e.g. "https://app.example.com", or from anywhere if origins contains "*".
+func newGRPCWebHandler(grpcServer *grpc.Server, tapHandle tap.ServerInHandle, origins []string) *grpcWebHandler {
+	allowed := make(map[string]bool)
+	for _, origin := range origins {
+		allowed[strings.ToLower(origin)] = true
+	}
+	wrapped := grpcweb.WrapHandler(
+		&tapHTTPHandler{next: grpcServer, tap: tapHandle},
+		grpcweb.WithEndpointsFunc(func() []string {
+			return grpcweb.ListGRPCResources(grpcServer)
+		}),
+		grpcweb.WithOriginFunc(func(origin string) bool {
+			return allowed["*"] || allowed[strings.ToLower(origin)]
+		}),
+		grpcweb.WithAllowedRequestHeaders([]string{"authorization", "x-grpc-web", "x-user-agent", "content-type"}),
+	)
+	return &grpcWebHandler{wrapped}
+}
+
+// Register the gRPC-Web endpoints with r.
+func (h *grpcWebHandler) Register(r *mux.Router) {
+	r.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return h.IsGrpcWebRequest(r) || h.IsAcceptableGrpcCorsRequest(r)
+	}).Handler(h)
+}
+
+// tapHTTPHandler runs the tap handler for gRPC requests via HTTP before
+// passing them to next.
+type tapHTTPHandler struct {
+	next http.Handler
+	tap  tap.ServerInHandle
+}
+
+func (h *tapHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, err := tapHTTPRequest(r.Context(), h.tap, r, r.URL.Path)
+	if err != nil {
+		// Respond like gRPC, with the status in a trailers-only response
+		st := status.Convert(err)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", strconv.Itoa(int(st.Code())))
+		w.Header().Set("Grpc-Message", st.Message())
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if _, ok := w.(http.CloseNotifier); !ok {
+		w = &closeNotifyResponseWriter{ResponseWriter: w, done: r.Context().Done()}
+	}
+	h.next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// closeNotifyResponseWriter adds http.CloseNotifier, which gRPC requires
+// for requests via HTTP, to response writers that lack it, e.g. the one
+// of gRPC-Web. It notifies when the request is done.
+type closeNotifyResponseWriter struct {
+	http.ResponseWriter
+	done <-chan struct{}
+}
+
+func (w *closeNotifyResponseWriter) CloseNotify() <-chan bool {
+	ch := make(chan bool, 1)
+	go func() {
+		<-w.done
+		ch <- true
+	}()
+	return ch
+}
+
+func (w *closeNotifyResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the standard grpc.health.v1.Health service.
+//
+// The serving status of every registered service is derived from the
+// checks in the registry, i.e. a service is only reported as SERVING
+// if the registry is ready. The empty service name stands for the
+// server as a whole and is always registered.
+type Server struct {
+	registry *Registry
+	services map[string]bool
+}
+
+// NewServer creates a new gRPC health server that reports the status
+// of the registry for the given services.
+func NewServer(registry *Registry, services ...string) *Server {
+	s := &Server{
+		registry: registry,
+		services: map[string]bool{"": true},
+	}
+	for _, service := range services {
+		s.services[service] = true
+	}
+	return s
+}
+
+// AuthFuncOverride lets health checks pass without credentials, so that
+// load balancers can probe the server. It satisfies the
+// ServiceAuthFuncOverride interface of go-grpc-middleware/auth.
+func (s *Server) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	return ctx, nil
+}
+
+// Check returns the current serving status of the requested service.
+func (s *Server) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !s.hasService(req.Service) {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+	return &healthpb.HealthCheckResponse{
+		Status: s.servingStatus(),
+	}, nil
+}
+
+// Watch sends the serving status of the requested service, and then
+// sends an update every time the status changes. Unknown services are
+// reported as SERVICE_UNKNOWN, as mandated by the health checking protocol.
+//
+// When the registry starts draining, Watch sends NOT_SERVING and ends the
+// stream, so that it doesn't keep a graceful stop of the server waiting.
+func (s *Server) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		// Get the channel before computing the status, so we don't miss an update
+		changed := s.registry.Changed()
+		draining := s.registry.Draining()
+
+		current := healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		if s.hasService(req.Service) {
+			current = s.servingStatus()
+		}
+		if current != last {
+			err := stream.Send(&healthpb.HealthCheckResponse{Status: current})
+			if err != nil {
+				return err
+			}
+			last = current
+		}
+		if draining {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		}
+	}
+}
+
+// hasService returns true if the service is registered.
+func (s *Server) hasService(service string) bool {
+	return s.services[service]
+}
+
+// servingStatus maps the readiness of the registry to the
+// serving status of the gRPC health checking protocol.
+func (s *Server) servingStatus() healthpb.HealthCheckResponse_ServingStatus {
+	if s.registry.Ready() {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
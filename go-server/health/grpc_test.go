@@ -0,0 +1,62 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// watchStream records the responses of a Watch call.
+type watchStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan healthpb.HealthCheckResponse_ServingStatus
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(res *healthpb.HealthCheckResponse) error {
+	s.sent <- res.Status
+	return nil
+}
+
+func TestWatchEndsWhenDraining(t *testing.T) {
+	registry := NewRegistry()
+	srv := NewServer(registry)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &watchStream{ctx: ctx, sent: make(chan healthpb.HealthCheckResponse_ServingStatus, 10)}
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Watch(&healthpb.HealthCheckRequest{}, stream)
+	}()
+
+	if want, have := healthpb.HealthCheckResponse_SERVING, <-stream.sent; want != have {
+		t.Fatalf("expected status %v, have %v", want, have)
+	}
+
+	registry.SetDraining(true)
+
+	select {
+	case have := <-stream.sent:
+		if want := healthpb.HealthCheckResponse_NOT_SERVING; want != have {
+			t.Fatalf("expected status %v, have %v", want, have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected NOT_SERVING when draining")
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected Watch to end without error, have %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Watch to end when draining")
+	}
+}
@@ -1,62 +1,295 @@
 package health
 
 import (
+	"encoding/json"
 	"net/http"
+	"sort"
 	"sync"
 )
 
-var (
-	mu              sync.RWMutex
-	healthzStatus   = http.StatusOK
-	readinessStatus = http.StatusOK
+// Kind specifies whether a check affects liveness, readiness, or both.
+type Kind uint
+
+const (
+	// Liveness checks make the server unhealthy when failing. Orchestrators
+	// typically restart a server that is not alive.
+	Liveness Kind = 1 << iota
+	// Readiness checks make the server unavailable when failing. Load
+	// balancers typically stop sending traffic to a server that is not ready.
+	Readiness
 )
 
+// String returns a textual representation of the kind.
+func (k Kind) String() string {
+	switch k {
+	case Liveness:
+		return "liveness"
+	case Readiness:
+		return "readiness"
+	case Liveness | Readiness:
+		return "liveness,readiness"
+	default:
+		return ""
+	}
+}
+
+// Registry keeps a set of named checks and computes the overall
+// liveness and readiness from them. The server is alive if all liveness
+// checks pass, and it is ready if it is alive, not draining, and all
+// readiness checks pass.
+type Registry struct {
+	mu       sync.RWMutex
+	checks   map[string]*Check
+	draining bool
+	changed  chan struct{}
+}
+
+// NewRegistry creates a new, empty registry.
+// An empty registry is both alive and ready.
+func NewRegistry() *Registry {
+	return &Registry{
+		checks:  make(map[string]*Check),
+		changed: make(chan struct{}),
+	}
+}
+
+// Register adds a check with the given name and kind. The check is
+// failing until Pass is called. Registering the same name twice
+// returns the existing check.
+func (r *Registry) Register(name string, kind Kind) *Check {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, found := r.checks[name]; found {
+		return c
+	}
+	c := &Check{
+		r:       r,
+		name:    name,
+		kind:    kind,
+		message: "not started",
+	}
+	r.checks[name] = c
+	r.notifyLocked()
+	return c
+}
+
+// Unregister removes the check with the given name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.checks, name)
+	r.notifyLocked()
+	r.mu.Unlock()
+}
+
+// Alive returns true if all liveness checks pass.
+func (r *Registry) Alive() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.passingLocked(Liveness)
+}
+
+// Ready returns true if the server is not draining and all liveness
+// and readiness checks pass.
+func (r *Registry) Ready() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !r.draining && r.passingLocked(Liveness|Readiness)
+}
+
+// Draining returns true if the server is draining, i.e. it is about
+// to go away and should not accept new work.
+func (r *Registry) Draining() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.draining
+}
+
+// SetDraining enables or disables draining. A draining server is
+// never ready, regardless of its checks.
+func (r *Registry) SetDraining(draining bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining == draining {
+		return
+	}
+	r.draining = draining
+	r.notifyLocked()
+}
+
 // HealthzStatus returns the current health status as a HTTP status code.
-func HealthzStatus() int {
-	mu.RLock()
-	defer mu.RUnlock()
-	return healthzStatus
+func (r *Registry) HealthzStatus() int {
+	if r.Alive() {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
 }
 
 // ReadinessStatus returns the current readiness status as a HTTP status code.
-func ReadinessStatus() int {
-	mu.RLock()
-	defer mu.RUnlock()
-	return readinessStatus
+func (r *Registry) ReadinessStatus() int {
+	if r.Ready() {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+// Changed returns a channel that is closed the next time a check
+// is registered, unregistered, or changes its state.
+func (r *Registry) Changed() <-chan struct{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.changed
+}
+
+// passingLocked returns true if all checks of the given kinds pass.
+// The caller must hold r.mu.
+func (r *Registry) passingLocked(kind Kind) bool {
+	for _, c := range r.checks {
+		if c.kind&kind != 0 && !c.ok {
+			return false
+		}
+	}
+	return true
+}
+
+// notifyLocked wakes up everybody waiting on Changed.
+// The caller must hold r.mu for writing.
+func (r *Registry) notifyLocked() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// report renders the overall status and the state of all checks
+// of the given kinds.
+func (r *Registry) report(kind Kind) (int, *Report) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := &Report{Status: "ok"}
+	code := http.StatusOK
+	if kind&Readiness != 0 {
+		report.Draining = r.draining
+	}
+	if !r.passingLocked(kind) || report.Draining {
+		report.Status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	var names []string
+	for name, c := range r.checks {
+		if c.kind&kind != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := r.checks[name]
+		report.Checks = append(report.Checks, CheckReport{
+			Name:     c.name,
+			Kind:     c.kind.String(),
+			OK:       c.ok,
+			Degraded: c.degraded,
+			Message:  c.message,
+		})
+		if c.degraded && report.Status == "ok" {
+			report.Status = "degraded"
+		}
+	}
+	return code, report
+}
+
+// Report is the JSON representation of the health or readiness status.
+// A degraded status still counts as healthy and ready.
+type Report struct {
+	Status   string        `json:"status"`
+	Draining bool          `json:"draining,omitempty"`
+	Checks   []CheckReport `json:"checks"`
+}
+
+// CheckReport is the JSON representation of a single check.
+type CheckReport struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	OK       bool   `json:"ok"`
+	Degraded bool   `json:"degraded,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// HealthzHandler returns the current health status, with a breakdown
+// of all liveness checks in the body.
+func (r *Registry) HealthzHandler(w http.ResponseWriter, req *http.Request) {
+	code, report := r.report(Liveness)
+	writeReport(w, code, report)
+}
+
+// ReadinessHandler returns the current readiness status, with a breakdown
+// of all liveness and readiness checks in the body.
+func (r *Registry) ReadinessHandler(w http.ResponseWriter, req *http.Request) {
+	code, report := r.report(Liveness | Readiness)
+	writeReport(w, code, report)
+}
+
+func writeReport(w http.ResponseWriter, code int, report *Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Check is a named check in a Registry. Components keep a reference
+// to their check and update it whenever their state changes.
+type Check struct {
+	r        *Registry
+	name     string
+	kind     Kind
+	ok       bool
+	degraded bool
+	message  string
+}
+
+// Name returns the name of the check.
+func (c *Check) Name() string {
+	return c.name
+}
+
+// OK returns true if the check is passing.
+func (c *Check) OK() bool {
+	c.r.mu.RLock()
+	defer c.r.mu.RUnlock()
+	return c.ok
 }
 
-// SetHealtzStatus sets the health status as a HTTP status code.
-func SetHealtzStatus(status int) {
-	mu.Lock()
-	healthzStatus = status
-	mu.Unlock()
+// Pass marks the check as passing.
+func (c *Check) Pass() {
+	c.set(true, false, "")
 }
 
-// SetReadinessStatus sets the readiness status as a HTTP status code.
-func SetReadinessStatus(status int) {
-	mu.Lock()
-	readinessStatus = status
-	mu.Unlock()
+// Degrade marks the check as passing, but degraded, with a message
+// describing why. Use it for components that keep working in a
+// reduced way, e.g. without a backend they depend on. A degraded check
+// doesn't affect liveness or readiness, but is reported as such.
+func (c *Check) Degrade(message string) {
+	c.set(true, true, message)
 }
 
-// HealthzHandler returns the current health status.
-func HealthzHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(HealthzStatus())
+// Degraded returns true if the check is passing, but degraded.
+func (c *Check) Degraded() bool {
+	c.r.mu.RLock()
+	defer c.r.mu.RUnlock()
+	return c.degraded
 }
 
-// ReadinessHandler returns the current readiness status.
-func ReadinessHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(ReadinessStatus())
+// Fail marks the check as failing, with a message describing why.
+func (c *Check) Fail(message string) {
+	c.set(false, false, message)
 }
 
-// ToggleHealthzStatusHandler toggles the current health status between
-// http.StatusOK and http.StatusServiceUnavailable.
-func ToggleHealthzStatusHandler(w http.ResponseWriter, r *http.Request) {
-	switch HealthzStatus() {
-	case http.StatusOK:
-		SetHealtzStatus(http.StatusServiceUnavailable)
-	case http.StatusServiceUnavailable:
-		SetHealtzStatus(http.StatusOK)
+func (c *Check) set(ok, degraded bool, message string) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	if c.ok == ok && c.degraded == degraded && c.message == message {
+		return
 	}
-	w.WriteHeader(http.StatusOK)
+	c.ok = ok
+	c.degraded = degraded
+	c.message = message
+	c.r.notifyLocked()
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LimiterStore keeps a rate limiting bucket per key, e.g. per user. It is bounded
+// in size and evicts the least recently used limiters when full, and
+// limiters that haven't been used for a while. To avoid a single lock
+// becoming a hot spot, the keys are spread over a number of shards,
+// each with its own lock and its own share of the maximum size.
+//
+// LimiterStore implements prometheus.Collector and reports its size and
+// the number of evictions.
+type LimiterStore struct {
+	lru      uint64 // number of evictions because the store was full
+	expired  uint64 // number of evictions because the limiter was idle
+	shards   []*limiterShard
+	ttl      time.Duration
+	now      func() time.Time
+	sizeDesc *prometheus.Desc
+	evicDesc *prometheus.Desc
+}
+
+type limiterShard struct {
+	mu      sync.Mutex
+	maxSize int
+	items   map[string]*list.Element
+	order   *list.List // front is most recently used
+}
+
+type limiterEntry struct {
+	key      string
+	bucket   *bucket
+	lastUsed time.Time
+}
+
+// NewLimiterStore creates a store with the given number of shards that
+// holds at most maxSize limiters. Limiters that haven't been used for
+// ttl are evicted. A ttl of zero disables idle expiry.
+func NewLimiterStore(shards, maxSize int, ttl time.Duration) *LimiterStore {
+	if shards <= 0 {
+		shards = 1
+	}
+	if maxSize < shards {
+		maxSize = shards
+	}
+	s := &LimiterStore{
+		shards: make([]*limiterShard, shards),
+		ttl:    ttl,
+		now:    time.Now,
+		sizeDesc: prometheus.NewDesc(
+			"grpc_demo_limiter_store_size",
+			"Number of rate limiters currently kept in the store.",
+			nil, nil,
+		),
+		evicDesc: prometheus.NewDesc(
+			"grpc_demo_limiter_store_evictions_total",
+			"Number of rate limiters evicted from the store, by reason.",
+			[]string{"reason"}, nil,
+		),
+	}
+	for i := range s.shards {
+		s.shards[i] = &limiterShard{
+			maxSize: maxSize / shards,
+			items:   make(map[string]*list.Element),
+			order:   list.New(),
+		}
+	}
+	return s
+}
+
+// Get returns the bucket for key. If there is none, or it has expired,
+// a new bucket is created.
+func (s *LimiterStore) Get(key string) *bucket {
+	shard := s.shard(key)
+	now := s.now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	s.expireLocked(shard, now)
+
+	if elem, found := shard.items[key]; found {
+		entry := elem.Value.(*limiterEntry)
+		entry.lastUsed = now
+		shard.order.MoveToFront(elem)
+		return entry.bucket
+	}
+
+	entry := &limiterEntry{
+		key:      key,
+		bucket:   new(bucket),
+		lastUsed: now,
+	}
+	shard.items[key] = shard.order.PushFront(entry)
+	for shard.order.Len() > shard.maxSize {
+		shard.removeLocked(shard.order.Back())
+		atomic.AddUint64(&s.lru, 1)
+	}
+	return entry.bucket
+}
+
+// Len returns the number of limiters in the store.
+func (s *LimiterStore) Len() int {
+	var n int
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		n += shard.order.Len()
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+// Describe implements prometheus.Collector.
+func (s *LimiterStore) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.sizeDesc
+	ch <- s.evicDesc
+}
+
+// Collect implements prometheus.Collector.
+func (s *LimiterStore) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(s.sizeDesc, prometheus.GaugeValue, float64(s.Len()))
+	ch <- prometheus.MustNewConstMetric(s.evicDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&s.lru)), "lru")
+	ch <- prometheus.MustNewConstMetric(s.evicDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&s.expired)), "expired")
+}
+
+// shard returns the shard responsible for key.
+func (s *LimiterStore) shard(key string) *limiterShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// expireLocked removes idle limiters, starting with the least recently
+// used. The caller must hold shard.mu.
+func (s *LimiterStore) expireLocked(shard *limiterShard, now time.Time) {
+	if s.ttl <= 0 {
+		return
+	}
+	for elem := shard.order.Back(); elem != nil; elem = shard.order.Back() {
+		if now.Sub(elem.Value.(*limiterEntry).lastUsed) < s.ttl {
+			return
+		}
+		shard.removeLocked(elem)
+		atomic.AddUint64(&s.expired, 1)
+	}
+}
+
+// removeLocked removes an element. The caller must hold shard.mu.
+func (shard *limiterShard) removeLocked(elem *list.Element) {
+	entry := shard.order.Remove(elem).(*limiterEntry)
+	delete(shard.items, entry.key)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// storeOp gets the bucket of key from a LimiterStore, after advancing
+// the clock of the store by advance.
+type storeOp struct {
+	key     string
+	advance time.Duration
+}
+
+func TestLimiterStore(t *testing.T) {
+	var manyKeys []storeOp
+	for i := 0; i < 100; i++ {
+		manyKeys = append(manyKeys, storeOp{key: fmt.Sprintf("user%d", i)})
+	}
+
+	tests := []struct {
+		name    string
+		shards  int
+		maxSize int
+		ttl     time.Duration
+		ops     []storeOp
+		kept    []string // keys whose bucket must still be there
+		evicted []string // keys whose bucket must be gone
+		maxLen  int
+	}{
+		{
+			name:    "evicts least recently used at capacity",
+			shards:  1,
+			maxSize: 2,
+			ops:     []storeOp{{key: "a"}, {key: "b"}, {key: "a"}, {key: "c"}},
+			kept:    []string{"a", "c"},
+			evicted: []string{"b"},
+			maxLen:  2,
+		},
+		{
+			name:    "splits capacity across shards",
+			shards:  4,
+			maxSize: 8,
+			ops:     manyKeys,
+			kept:    []string{"user99"},
+			maxLen:  8,
+		},
+		{
+			name:    "expires idle limiters",
+			shards:  1,
+			maxSize: 10,
+			ttl:     time.Minute,
+			ops:     []storeOp{{key: "a"}, {key: "b", advance: 30 * time.Second}, {key: "c", advance: 40 * time.Second}},
+			kept:    []string{"b", "c"},
+			evicted: []string{"a"},
+			maxLen:  2,
+		},
+		{
+			name:    "keeps idle limiters without ttl",
+			shards:  1,
+			maxSize: 10,
+			ops:     []storeOp{{key: "a"}, {key: "b", advance: time.Hour}},
+			kept:    []string{"a", "b"},
+			maxLen:  2,
+		},
+		{
+			name:    "shares a key across shards",
+			shards:  16,
+			maxSize: 1600,
+			ops:     append(append([]storeOp{{key: "alice"}}, manyKeys...), storeOp{key: "alice"}),
+			kept:    []string{"alice"},
+			maxLen:  101,
+		},
+	}
+	for _, tt := range tests {
+		now := time.Now()
+		s := NewLimiterStore(tt.shards, tt.maxSize, tt.ttl)
+		s.now = func() time.Time { return now }
+
+		buckets := make(map[string]*bucket)
+		for _, op := range tt.ops {
+			now = now.Add(op.advance)
+			b := s.Get(op.key)
+			if prev, found := buckets[op.key]; found && prev != b {
+				t.Errorf("%s: expected %s to keep its bucket", tt.name, op.key)
+			}
+			buckets[op.key] = b
+		}
+		if n := s.Len(); n > tt.maxLen {
+			t.Errorf("%s: expected at most %d limiters, have %d", tt.name, tt.maxLen, n)
+		}
+		// Check the kept keys first, as getting an evicted key adds it again
+		for _, key := range tt.kept {
+			if s.Get(key) != buckets[key] {
+				t.Errorf("%s: expected %s to be kept", tt.name, key)
+			}
+		}
+		for _, key := range tt.evicted {
+			if s.Get(key) == buckets[key] {
+				t.Errorf("%s: expected %s to be evicted", tt.name, key)
+			}
+		}
+	}
+}
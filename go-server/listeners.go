@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/soheilhy/cmux"
+)
+
+// Protocols that can be served on a listener.
+const (
+	protoGRPC    = "grpc"     // gRPC, including the health service
+	protoMetrics = "metrics"  // Prometheus metrics via /metrics
+	protoHealth  = "health"   // HTTP health checks via /healthz and /readiness
+	protoAdmin   = "admin"    // the admin API below /admin
+	protoREST    = "rest"     // the REST/JSON front below /v1
+	protoGRPCWeb = "grpc-web" // gRPC-Web for browsers, via HTTP/1.1
+)
+
+// allProtocols lists all protocols that can be served.
+var allProtocols = []string{protoGRPC, protoMetrics, protoHealth, protoAdmin, protoREST, protoGRPCWeb}
+
+// protocols is a set of protocols to serve on a listener.
+type protocols map[string]bool
+
+// parseProtocols parses a comma-separated list of protocols,
+// e.g. "grpc,metrics". The list "all" stands for all protocols.
+func parseProtocols(s string) (protocols, error) {
+	p := make(protocols)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+		case "all":
+			for _, name := range allProtocols {
+				p[name] = true
+			}
+		case protoGRPC, protoMetrics, protoHealth, protoAdmin, protoREST, protoGRPCWeb:
+			p[name] = true
+		default:
+			return nil, fmt.Errorf("unknown protocol %q; valid protocols are %s", name, strings.Join(allProtocols, ", "))
+		}
+	}
+	return p, nil
+}
+
+// listenerProtocols returns the protocols to serve via TLS and unencrypted
+// on the main listener, from the -tls, -tls-protocols and
+// -plaintext-protocols flags. TLS protocols default to all with TLS, and
+// plaintext protocols to all without it. With a separate admin listener,
+// metrics, health checks and the admin API are removed from both sides.
+//
+// It returns an error for settings that would be ignored, i.e. TLS
+// protocols without TLS, and for listeners left without protocols.
+func listenerProtocols(tls bool, tlsList, plainList string, separateAdmin bool) (tlsProtocols, plainProtocols protocols, err error) {
+	if !tls && tlsList != "" {
+		return nil, nil, fmt.Errorf("TLS protocols %q require -tls", tlsList)
+	}
+	if tls && tlsList == "" {
+		tlsList = "all"
+	}
+	if !tls && plainList == "" {
+		plainList = "all"
+	}
+	tlsProtocols, err = parseProtocols(tlsList)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid TLS protocols: %v", err)
+	}
+	plainProtocols, err = parseProtocols(plainList)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid plaintext protocols: %v", err)
+	}
+
+	// With TLS, the plaintext side is optional, so we only check it if
+	// any plaintext protocols are given.
+	sides := []struct {
+		name  string
+		p     protocols
+		serve bool
+	}{
+		{"TLS", tlsProtocols, tls},
+		{"plaintext", plainProtocols, !tls || len(plainProtocols) > 0},
+	}
+	for _, side := range sides {
+		if !side.serve {
+			continue
+		}
+		if len(side.p) == 0 {
+			return nil, nil, fmt.Errorf("no %s protocols to serve", side.name)
+		}
+		if separateAdmin {
+			delete(side.p, protoMetrics)
+			delete(side.p, protoHealth)
+			delete(side.p, protoAdmin)
+			if len(side.p) == 0 {
+				return nil, nil, fmt.Errorf("no %s protocols left to serve, as metrics, health and admin are served on the admin address", side.name)
+			}
+		}
+	}
+	return tlsProtocols, plainProtocols, nil
+}
+
+// HTTP returns true if any of the protocols is served via HTTP.
+func (p protocols) HTTP() bool {
+	return p[protoMetrics] || p[protoHealth] || p[protoAdmin] || p[protoREST] || p[protoGRPCWeb]
+}
+
+// String returns the protocols as a sorted, comma-separated list.
+func (p protocols) String() string {
+	var names []string
+	for name, ok := range p {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// matchProtocols returns a listener for gRPC and one for HTTP from m,
+// if p contains any of the respective protocols. Otherwise the listener
+// is nil, and m closes connections of that kind.
+func matchProtocols(m cmux.CMux, p protocols) (grpclis, httplis net.Listener) {
+	if p.HTTP() {
+		httplis = m.Match(cmux.HTTP1Fast())
+	}
+	if p[protoGRPC] {
+		// grpclis = m.Match(cmux.HTTP2HeaderField("content-type", "application/grpc"))
+		grpclis = m.Match(cmux.Any())
+	}
+	return grpclis, httplis
+}
+
+// onceCloseListener is a listener that can be closed more than once.
+// With cmux, closing any of the matched listeners closes the root
+// listener, and we close all of them on shutdown.
+type onceCloseListener struct {
+	net.Listener
+
+	once sync.Once
+	err  error
+}
+
+// Close closes the listener once, and returns the same error afterwards.
+func (l *onceCloseListener) Close() error {
+	l.once.Do(func() { l.err = l.Listener.Close() })
+	return l.err
+}
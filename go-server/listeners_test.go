@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListenerProtocolsRejectsTLSProtocolsWithoutTLS(t *testing.T) {
+	_, _, err := listenerProtocols(false, "grpc", "", false)
+	if err == nil {
+		t.Fatal("expected an error for TLS protocols without -tls")
+	}
+	if want, have := "require -tls", err.Error(); !strings.Contains(have, want) {
+		t.Errorf("expected error to contain %q, have %q", want, have)
+	}
+}
+
+func TestListenerProtocolsRejectsEmptyListenerWithAdminAddr(t *testing.T) {
+	tests := []struct {
+		name      string
+		side      string
+		tls       bool
+		tlsList   string
+		plainList string
+	}{
+		{"plaintext only", "plaintext", false, "", "metrics,health"},
+		{"TLS only", "TLS", true, "admin", ""},
+		{"plaintext next to TLS", "plaintext", true, "grpc", "metrics,health"},
+	}
+	for _, tt := range tests {
+		_, _, err := listenerProtocols(tt.tls, tt.tlsList, tt.plainList, true)
+		if err == nil {
+			t.Errorf("%s: expected an error for a listener without protocols", tt.name)
+			continue
+		}
+		if want, have := "no "+tt.side+" protocols left", err.Error(); !strings.Contains(have, want) {
+			t.Errorf("%s: expected error to contain %q, have %q", tt.name, want, have)
+		}
+	}
+}
+
+func TestListenerProtocolsWithAdminAddr(t *testing.T) {
+	tlsProtocols, plainProtocols, err := listenerProtocols(true, "", "metrics,grpc", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "grpc,grpc-web,rest", tlsProtocols.String(); want != have {
+		t.Errorf("expected TLS protocols %q, have %q", want, have)
+	}
+	if want, have := "grpc", plainProtocols.String(); want != have {
+		t.Errorf("expected plaintext protocols %q, have %q", want, have)
+	}
+}
@@ -14,11 +14,14 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/coreos/etcd/clientv3"
 	etcdnaming "github.com/coreos/etcd/clientv3/naming"
 	"github.com/go-kit/kit/log"
+	"github.com/go-redis/redis"
 	grpcmw "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	grpcopentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
@@ -26,10 +29,12 @@ import (
 	"github.com/olivere/randport"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/soheilhy/cmux"
-	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/grpclog"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/naming"
+	"google.golang.org/grpc/reflection"
 
 	"github.com/gorilla/mux"
 	"github.com/olivere/grpc-demo/go-server/health"
@@ -39,6 +44,10 @@ import (
 const (
 	// serviceName is the name under which the server is registered in client-side load balancers.
 	serviceName = "grpc-demo-example"
+
+	// exampleServiceName is the fully-qualified name of the Example service,
+	// as reported by the gRPC health service.
+	exampleServiceName = "com.altf4.grpc.Example"
 )
 
 var (
@@ -59,13 +68,44 @@ func envString(name, defaults string) string {
 
 func main() {
 	var (
-		disco    = flag.String("disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
-		addr     = flag.String("addr", envString("ADDR", "localhost:10000"), "Host and port to bind to")
-		tls      = flag.Bool("tls", false, "Enabled TLS")
-		certFile = flag.String("cert", "", "Certificate file")
-		keyFile  = flag.String("key", "", "Key file")
-		qps      = flag.Float64("qps", 5, "Queries per second in rate limiter")
-		burst    = flag.Int("burst", 1, "Burst in rate limiter")
+		disco     = flag.String("disco", envString("DISCO", ""), "Service discovery mechanism (blank or etcd)")
+		addr      = flag.String("addr", envString("ADDR", "localhost:10000"), "Host and port to bind to")
+		adminAddr = flag.String("admin-addr", envString("ADMIN_ADDR", ""), "Host and port to serve metrics, health checks and the admin API on, instead of -addr (blank to serve them on -addr)")
+		tls       = flag.Bool("tls", false, "Enabled TLS")
+		certFile  = flag.String("cert", "", "Certificate file")
+		keyFile   = flag.String("key", "", "Key file")
+		tlsProtos = flag.String("tls-protocols", envString("TLS_PROTOCOLS", ""), "Protocols to serve over TLS with -tls (comma-separated list of grpc, metrics, health, admin, rest and grpc-web, or all); defaults to all")
+		plaintext = flag.String("plaintext-protocols", envString("PLAINTEXT_PROTOCOLS", ""), "Protocols to serve unencrypted (comma-separated list of grpc, metrics, health, admin, rest and grpc-web, or all); defaults to all without -tls and to none with -tls, which serves TLS only")
+		certPoll  = flag.Duration("cert-reload-interval", 30*time.Second, "How often to check the certificate and key files for changes (0 to only reload on SIGHUP)")
+		origins   = flag.String("grpc-web-origins", envString("GRPC_WEB_ORIGINS", ""), "Origins that browsers may send cross-origin gRPC-Web requests from (comma-separated list, or * for any; blank for same-origin only)")
+		clientCA  = flag.String("clientCA", envString("CLIENT_CA", ""), "Require and verify client certificates signed by the CAs in this file (requires -tls)")
+		qps       = flag.Float64("qps", 5, "Queries per second in rate limiter")
+		burst     = flag.Int("burst", 1, "Burst in rate limiter")
+		policy    = flag.String("policy", envString("POLICY", ""), "JSON file with rate limits per method and user tier; reloaded on SIGHUP (overrides -qps and -burst)")
+		limiter   = flag.String("limiter", envString("LIMITER", "memory"), "Where to keep rate limits (memory or redis)")
+		redisAddr = flag.String("redis-addr", envString("REDIS_ADDR", "localhost:6379"), "Host and port of the Redis server for -limiter=redis")
+		maxUsers  = flag.Int("limiter-max-users", 10000, "Maximum number of users to keep a rate limiter for")
+		idleTTL   = flag.Duration("limiter-ttl", 10*time.Minute, "Evict the rate limiter of a user after being idle for this long")
+		shards    = flag.Int("limiter-shards", 16, "Number of lock shards in the rate limiter store")
+		userCalls = flag.Int("max-calls-per-user", 20, "Maximum number of unary calls a user may have in flight at the same time (0 for no limit)")
+		userStrms = flag.Int("max-streams-per-user", 10, "Maximum number of streams, e.g. Ticker, a user may have open at the same time (0 for no limit)")
+		auth      = flag.String("auth", envString("AUTH", ""), "How to authenticate clients (token, jwt or cert; defaults to cert with -clientCA, token otherwise)")
+		tokens    = flag.String("tokens", envString("TOKENS", ""), "JSON file with API keys for -auth=token, e.g. ../etc/tokens.json with the demo keys")
+		jwtKey    = flag.String("jwt-key", envString("JWT_KEY", ""), "RSA public key in PEM format (RS256) or shared secret (HS256) to verify tokens with for -auth=jwt")
+		jwtIss    = flag.String("jwt-issuer", envString("JWT_ISSUER", ""), "Required issuer of tokens for -auth=jwt (blank to accept any)")
+		jwtAud    = flag.String("jwt-audience", envString("JWT_AUDIENCE", ""), "Required audience of tokens for -auth=jwt (blank to accept any)")
+		access    = flag.String("access", envString("ACCESS_POLICY", ""), "JSON file with the roles that may call each method; reloaded on SIGHUP (blank to allow all authenticated users)")
+		tickerKey = flag.String("ticker-key", envString("TICKER_KEY", ""), "Shared secret to sign Ticker resume tokens with; must be the same on all servers (blank for a random key, so that streams can only be resumed on this server until it restarts)")
+		reflect   = flag.Bool("reflection", false, "Register the gRPC server reflection service, e.g. for go-client list and describe")
+		htpasswd  = flag.String("htpasswd", envString("HTPASSWD", ""), "htpasswd file with users of the admin API (blank to disable the admin API)")
+		grace     = flag.Duration("drain-grace", 5*time.Second, "Time to wait after deregistering before stopping the server on shutdown")
+		timeout   = flag.Duration("drain-timeout", 30*time.Second, "Time to wait for in-flight RPCs on shutdown before forcing the server to stop")
+		kaTime    = flag.Duration("keepalive-time", time.Minute, "Ping clients after the connection has been idle for this long, to detect broken connections")
+		kaTimeout = flag.Duration("keepalive-timeout", 20*time.Second, "Close the connection if a ping isn't answered within this time")
+		kaMinTime = flag.Duration("keepalive-min-time", 30*time.Second, "Minimum time between client pings; clients that ping more often are disconnected")
+		connAge   = flag.Duration("max-connection-age", 5*time.Minute, "Ask clients to reconnect after a connection has been open for this long (±10%), to rebalance them across servers (0 for no limit)")
+		connGrace = flag.Duration("max-connection-age-grace", 30*time.Second, "Time that RPCs, e.g. Ticker streams, may take to finish after -max-connection-age before the connection is closed (0 for no limit)")
+		maxStrms  = flag.Uint("max-concurrent-streams", 100, "Maximum number of concurrent streams and calls per connection (0 for no limit)")
 	)
 	flag.Parse()
 
@@ -88,8 +128,31 @@ func main() {
 		*addr = net.JoinHostPort(host, strconv.Itoa(port))
 	}
 
+	// Configure protocols per side
+	tlsProtocols, plainProtocols, err := listenerProtocols(*tls, *tlsProtos, *plaintext, *adminAddr != "")
+	if err != nil {
+		logger.Log("msg", "Invalid protocols", "tlsProtocols", *tlsProtos, "plaintextProtocols", *plaintext, "adminAddr", *adminAddr, "err", err)
+		os.Exit(1)
+	}
+
 	// Create server
-	srv := NewServer(logger)
+	key := []byte(*tickerKey)
+	if len(key) == 0 {
+		key, err = newTickerKey()
+		if err != nil {
+			logger.Log("msg", "Cannot create ticker key", "err", err)
+			os.Exit(1)
+		}
+		logger.Log("msg", "No -ticker-key given; Ticker streams can only be resumed on this server until it restarts")
+	}
+	srv := NewServer(logger, key)
+
+	// Health checks
+	healthRegistry := health.NewRegistry()
+
+	// Graceful shutdown
+	shutdown := newShutdownSequence(logger, healthRegistry, *grace, *timeout)
+	defer shutdown.Deregister()
 
 	// Create listener
 	lis, err := net.Listen("tcp", *addr)
@@ -97,6 +160,18 @@ func main() {
 		logger.Log("msg", "Listen failed", "err", err)
 		os.Exit(1)
 	}
+	lis = &onceCloseListener{Listener: lis}
+
+	// Create a separate listener for metrics, health checks and the admin
+	// API; listenerProtocols has removed them from the main listener
+	var adminlis net.Listener
+	if *adminAddr != "" {
+		adminlis, err = net.Listen("tcp", *adminAddr)
+		if err != nil {
+			logger.Log("msg", "Listen failed", "addr", *adminAddr, "err", err)
+			os.Exit(1)
+		}
+	}
 
 	// Service discovery mechanism
 	switch *disco {
@@ -113,157 +188,449 @@ func main() {
 			logger.Log("msg", "Cannot register service in etcd", "service", serviceName, "addr", *addr, "err", err)
 			os.Exit(1)
 		}
-		// Unregister when done
-		defer resolver.Update(context.Background(), serviceName, naming.Update{Op: naming.Delete, Addr: *addr})
+		etcdCheck := healthRegistry.Register("etcd", health.Readiness)
+		etcdCheck.Pass()
+		// Unregister when shutting down
+		shutdown.OnDeregister(func() {
+			etcdCheck.Fail("deregistered")
+			err := resolver.Update(context.Background(), serviceName, naming.Update{Op: naming.Delete, Addr: *addr})
+			if err != nil {
+				logger.Log("msg", "Cannot deregister service in etcd", "service", serviceName, "addr", *addr, "err", err)
+			}
+		})
 	}
 
 	// Server options
 	var pool *x509.CertPool
-	var cert tlspkg.Certificate
+	var certs *certWatcher
 	var opts []grpc.ServerOption
 	if *tls {
 		var err error
-		cert, err = tlspkg.LoadX509KeyPair(*certFile, *keyFile)
+		certs, err = newCertWatcher(logger, *certFile, *keyFile)
 		if err != nil {
 			logger.Log("msg", "Cannot load certificate", "err", err)
 			os.Exit(1)
 		}
+		prometheus.MustRegister(certs)
+		if *certPoll > 0 {
+			go certs.Watch(*certPoll)
+		}
 
-		// Create pool to trust
-		caCert, err := ioutil.ReadFile(*certFile)
+		// Create pool of CAs to verify client certificates with
+		if *clientCA != "" {
+			caCert, err := ioutil.ReadFile(*clientCA)
+			if err != nil {
+				logger.Log("msg", "Cannot load client CA", "clientCA", *clientCA, "err", err)
+				os.Exit(1)
+			}
+			pool = x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caCert) {
+				logger.Log("msg", "No certificates found in client CA", "clientCA", *clientCA)
+				os.Exit(1)
+			}
+		}
+
+		// We don't need the instruct gRPC to do TLS because we are using cmux to proxy TLS,
+		// but we let gRPC know about the TLS connection state, e.g. for client certificates
+		// creds := credentials.NewServerTLSFromCert(&cert)
+		opts = append(opts, grpc.Creds(tlsInfoCredentials{}))
+	} else if *clientCA != "" {
+		logger.Log("msg", "Client certificates require -tls", "clientCA", *clientCA)
+		os.Exit(1)
+	}
+
+	if *auth == "" {
+		if *clientCA != "" {
+			*auth = "cert"
+		} else {
+			*auth = "token"
+		}
+	}
+	var authenticator Authenticator
+	switch *auth {
+	case "cert":
+		if *clientCA == "" {
+			logger.Log("msg", "Authentication with client certificates requires -clientCA", "auth", *auth)
+			os.Exit(1)
+		}
+		authenticator = NewCertAuthenticator()
+	case "token":
+		if *tokens == "" {
+			logger.Log("msg", "Authentication with API keys requires -tokens", "auth", *auth)
+			os.Exit(1)
+		}
+		authenticator, err = NewStaticAuthenticator(*tokens)
 		if err != nil {
-			logger.Log("msg", "Cannot load certificate", "err", err)
+			logger.Log("msg", "Cannot load tokens", "tokens", *tokens, "err", err)
 			os.Exit(1)
 		}
-		pool = x509.NewCertPool()
-		pool.AppendCertsFromPEM(caCert)
+	case "jwt":
+		authenticator, err = NewJWTAuthenticator(*jwtKey, *jwtIss, *jwtAud)
+		if err != nil {
+			logger.Log("msg", "Cannot load JWT key", "key", *jwtKey, "err", err)
+			os.Exit(1)
+		}
+	default:
+		logger.Log("msg", "Invalid authentication method", "auth", *auth)
+		os.Exit(1)
+	}
 
-		// We don't need the instruct gRPC to do TLS because we are using cmux to proxy TLS
-		// creds := credentials.NewServerTLSFromCert(&cert)
-		// opts = append(opts, grpc.Creds(creds))
+	var rateLimiter RateLimiter
+	switch *limiter {
+	case "memory":
+		limiters := NewLimiterStore(*shards, *maxUsers, *idleTTL)
+		prometheus.MustRegister(limiters)
+		rateLimiter = NewMemoryRateLimiter(limiters)
+	case "redis":
+		redisClient := redis.NewClient(&redis.Options{Addr: *redisAddr})
+		defer redisClient.Close()
+		rateLimiter = NewRedisRateLimiter(redisClient, serviceName+":ratelimit:")
+	default:
+		logger.Log("msg", "Invalid rate limiter", "limiter", *limiter)
+		os.Exit(1)
+	}
+
+	ratePolicy := &RatePolicy{Default: &Limit{QPS: *qps, Burst: *burst}}
+	if *policy != "" {
+		ratePolicy, err = LoadRatePolicy(*policy)
+		if err != nil {
+			logger.Log("msg", "Cannot load rate policy", "policy", *policy, "err", err)
+			os.Exit(1)
+		}
 	}
 
+	tapMetrics := NewMetrics()
+	prometheus.MustRegister(tapMetrics)
 	tap := NewTapHandler(
-		NewMetrics(),
-		rate.Limit(*qps),
-		*burst,
+		logger,
+		tapMetrics,
+		authenticator,
+		rateLimiter,
+		ratePolicy,
 	)
+	tap.RegisterHealthCheck(healthRegistry)
+
+	var accessPolicy *AccessPolicy
+	if *access != "" {
+		accessPolicy, err = LoadAccessPolicy(*access)
+		if err != nil {
+			logger.Log("msg", "Cannot load access policy", "access", *access, "err", err)
+			os.Exit(1)
+		}
+	}
+	authz := NewAuthorizer(logger, accessPolicy)
+
+	concurrency := NewConcurrencyLimiter(*userCalls, *userStrms)
+	prometheus.MustRegister(concurrency)
 
 	// Common options
 	// opts = append(opts, grpc.MaxRecvMsgSize(1<<20)) // 1MB
 	opts = append(opts, grpc.InTapHandle(tap.Handle))
 
-	// gRPC middleware
-	opts = append(opts, grpc.StreamInterceptor(grpcmw.ChainStreamServer(
+	// Connection management: With round-robin load balancing via etcd,
+	// clients connect once and stay on the servers they found at the time.
+	// Limiting the age of connections makes them reconnect from time to time,
+	// so they spread across servers that were added since. gRPC tells clients
+	// via GOAWAY, and closes the connection after the grace period, ending
+	// streams that are still open with Unavailable. Clients then resume them
+	// on a new connection, e.g. go-client's ResumableTicker.
+	opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+		Time:                  *kaTime,
+		Timeout:               *kaTimeout,
+		MaxConnectionAge:      *connAge,
+		MaxConnectionAgeGrace: *connGrace,
+	}))
+	opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime: *kaMinTime,
+		// Clients may ping idle connections, e.g. to other servers in the
+		// round-robin pool, to notice when they go away
+		PermitWithoutStream: true,
+	}))
+	if *maxStrms > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(uint32(*maxStrms)))
+	}
+
+	// gRPC middleware, shared with the REST front
+	streamInterceptor := grpcmw.ChainStreamServer(
 		grpcprom.StreamServerInterceptor,
 		grpcopentracing.StreamServerInterceptor(),
-		grpcauth.StreamServerInterceptor(authenticate),
-	)))
-	opts = append(opts, grpc.UnaryInterceptor(grpcmw.ChainUnaryServer(
+		tap.StreamServerInterceptor,
+		grpcauth.StreamServerInterceptor(authFunc(authenticator)),
+		authz.StreamServerInterceptor,
+		concurrency.StreamServerInterceptor,
+	)
+	unaryInterceptor := grpcmw.ChainUnaryServer(
 		grpcprom.UnaryServerInterceptor,
 		grpcopentracing.UnaryServerInterceptor(),
-		grpcauth.UnaryServerInterceptor(authenticate),
-	)))
+		tap.UnaryServerInterceptor,
+		grpcauth.UnaryServerInterceptor(authFunc(authenticator)),
+		authz.UnaryServerInterceptor,
+		concurrency.UnaryServerInterceptor,
+	)
+	opts = append(opts, grpc.StreamInterceptor(streamInterceptor))
+	opts = append(opts, grpc.UnaryInterceptor(unaryInterceptor))
 
 	grpcServer := grpc.NewServer(opts...)
+	shutdown.grpcServer = grpcServer
+	shutdown.OnStop(tap.Drain)
+	shutdown.OnStop(srv.Drain)
 	pb.RegisterExampleServer(grpcServer, srv)
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer(healthRegistry, exampleServiceName))
+	if *reflect {
+		// Reflection is authenticated and authorized like any other service,
+		// as it reveals the API of the server
+		reflection.Register(grpcServer)
+	}
 	grpcprom.Register(grpcServer)
 
-	// Multiplex connections
-	//
-	// We have two modes of operating. When TLS is enabled, we serve both gRPC and
-	// HTTP over TLS, i.e. Prometheus metrics are only available via https://.../metrics.
-	//
-	// When TLS is disabled, we are serving both gRPC and HTTP unencrypted.
-	//
-	// Notice that we could change this via recursive multiplexing in cmux:
-	// https://godoc.org/github.com/soheilhy/cmux#ex-package--RecursiveCmux
-	// That would allow us to serve e.g. HTTP over TLS as well as unencrypted.
-	if *tls {
-		tlscfg := &tlspkg.Config{
-			Certificates: []tlspkg.Certificate{cert},
-			RootCAs:      pool,
+	// Admin API
+	var admin *adminHandler
+	if *htpasswd != "" {
+		admin, err = newAdminHandler(logger, healthRegistry, *htpasswd)
+		if err != nil {
+			logger.Log("msg", "Cannot load htpasswd file", "htpasswd", *htpasswd, "err", err)
+			os.Exit(1)
 		}
-		lis = tlspkg.NewListener(lis, tlscfg)
 	}
-	tcpmux := cmux.New(lis)
-	httplis := tcpmux.Match(cmux.HTTP1Fast())
-	// grpclis := tcpmux.Match(cmux.HTTP2HeaderField("content-type", "application/grpc"))
-	// httplis := tcpmux.Match(cmux.Any())
-	grpclis := tcpmux.Match(cmux.Any())
-	// _ = httplis
-	// _ = grpclis
 
-	errc := make(chan error, 1)
+	// REST/JSON front
+	rest := newRESTHandler(logger, srv, tap.Handle, unaryInterceptor, streamInterceptor)
 
-	// gRPC listener
-	go func() {
-		err := grpcServer.Serve(grpclis)
-		if err != cmux.ErrListenerClosed {
-			errc <- err
-		} else {
-			errc <- nil
+	// gRPC-Web for browsers
+	var webOrigins []string
+	for _, origin := range strings.Split(*origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			webOrigins = append(webOrigins, origin)
 		}
-	}()
+	}
+	grpcWeb := newGRPCWebHandler(grpcServer, tap.Handle, webOrigins)
 
-	// HTTP listener
-	go func() {
+	errc := make(chan error, 1)
+
+	// newHTTPHandler returns the HTTP endpoints of the given protocols.
+	// name tells the listeners apart in logs.
+	newHTTPHandler := func(name string, p protocols) http.Handler {
 		r := mux.NewRouter()
 
 		// Health endpoints
-		r.HandleFunc("/healthz", health.HealthzHandler)
-		r.HandleFunc("/healthz/status", health.ToggleHealthzStatusHandler)
-		r.HandleFunc("/readiness", health.ReadinessHandler)
-		r.HandleFunc("/readiness/status", health.ToggleHealthzStatusHandler)
-		defer func() {
-			health.SetHealtzStatus(http.StatusServiceUnavailable)
-			health.SetReadinessStatus(http.StatusServiceUnavailable)
-		}()
+		if p[protoHealth] {
+			r.HandleFunc("/healthz", healthRegistry.HealthzHandler)
+			r.HandleFunc("/readiness", healthRegistry.ReadinessHandler)
+		}
+
+		// Admin endpoints
+		if p[protoAdmin] && admin != nil {
+			admin.Register(r)
+		}
+
+		// REST endpoints
+		if p[protoREST] {
+			rest.Register(r)
+		}
+
+		// gRPC-Web endpoints
+		if p[protoGRPCWeb] {
+			grpcWeb.Register(r)
+		}
 		r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			logger.Log("msg", "unmatched HTTP request", "url", r.RequestURI)
+			logger.Log("msg", "unmatched HTTP request", "url", r.RequestURI, "listener", name)
+			http.NotFound(w, r)
 		})
 
 		// Metrics endpoints
-		r.Handle("/metrics", prometheus.Handler())
+		if p[protoMetrics] {
+			r.Handle("/metrics", prometheus.Handler())
+		}
+		return r
+	}
 
+	// serveHTTP serves HTTP requests on lis with handler.
+	serveHTTP := func(lis net.Listener, handler http.Handler) {
 		httpsrv := &http.Server{
-			Addr:    *addr,
-			Handler: r,
+			Addr:        lis.Addr().String(),
+			Handler:     handler,
+			ConnContext: withConnAuthInfo,
 		}
-		err := httpsrv.Serve(httplis)
-		if err != cmux.ErrListenerClosed {
-			errc <- err
-		} else {
-			errc <- nil
+		shutdown.httpServers = append(shutdown.httpServers, httpsrv)
+		go func() {
+			err := httpsrv.Serve(lis)
+			if err != cmux.ErrListenerClosed && err != http.ErrServerClosed {
+				errc <- err
+			} else {
+				errc <- nil
+			}
+		}()
+	}
+
+	// serve serves the given protocols on the connections of m, with gRPC
+	// and HTTP being told apart by cmux. name tells the listeners apart
+	// in health checks and logs.
+	serve := func(name string, m cmux.CMux, p protocols) {
+		grpclis, httplis := matchProtocols(m, p)
+
+		// gRPC listener
+		if grpclis != nil {
+			grpcCheck := healthRegistry.Register("grpc"+name, health.Liveness|health.Readiness)
+			go func() {
+				grpcCheck.Pass()
+				err := grpcServer.Serve(grpclis)
+				grpcCheck.Fail("listener closed")
+				if err != cmux.ErrListenerClosed {
+					errc <- err
+				} else {
+					errc <- nil
+				}
+			}()
+		}
+
+		// HTTP listener
+		if httplis != nil {
+			serveHTTP(httplis, newHTTPHandler(name, p))
+		}
+
+		// Start multiplexer
+		go func() { errc <- m.Serve() }()
+	}
+
+	// Separate listener for metrics, health checks and the admin API
+	if adminlis != nil {
+		serveHTTP(adminlis, newHTTPHandler("-admin", protocols{
+			protoMetrics: true,
+			protoHealth:  true,
+			protoAdmin:   true,
+		}))
+	}
+
+	// Multiplex connections
+	//
+	// We have three modes of operating. When TLS is disabled, we are serving
+	// both gRPC and HTTP unencrypted.
+	//
+	// When TLS is enabled, we serve both gRPC and HTTP over TLS, i.e. Prometheus
+	// metrics are only available via https://.../metrics.
+	//
+	// When TLS is enabled and plaintext protocols are given as well, we use
+	// recursive multiplexing in cmux: we first tell TLS handshakes from plaintext,
+	// then gRPC from HTTP on both sides, serving only the protocols enabled for
+	// the side. That allows us to serve e.g. gRPC over TLS only, and metrics
+	// unencrypted for Prometheus. See
+	// https://godoc.org/github.com/soheilhy/cmux#ex-package--RecursiveCmux
+	var tlscfg *tlspkg.Config
+	if *tls {
+		tlscfg = &tlspkg.Config{
+			GetCertificate: certs.GetCertificate,
+		}
+		if pool != nil {
+			tlscfg.ClientAuth = tlspkg.RequireAndVerifyClientCert
+			tlscfg.ClientCAs = pool
 		}
-	}()
+	}
+	switch {
+	case !*tls:
+		serve("", cmux.New(lis), plainProtocols)
+	case len(plainProtocols) == 0:
+		serve("", cmux.New(tlspkg.NewListener(lis, tlscfg)), tlsProtocols)
+	default:
+		tcpmux := cmux.New(lis)
+		tlslis := tcpmux.Match(cmux.TLS())
+		plainlis := tcpmux.Match(cmux.Any())
+		serve("-tls", cmux.New(tlspkg.NewListener(tlslis, tlscfg)), tlsProtocols)
+		serve("-plaintext", cmux.New(plainlis), plainProtocols)
+		go func() { errc <- tcpmux.Serve() }()
+	}
 
-	// Start multiplexer
-	go func() { errc <- tcpmux.Serve() }()
+	// Reload the certificate, rate and access policies on SIGHUP
+	if certs != nil || *policy != "" || *access != "" {
+		go func() {
+			hupc := make(chan os.Signal, 1)
+			signal.Notify(hupc, syscall.SIGHUP)
+			for range hupc {
+				if certs != nil {
+					if err := certs.Reload(); err != nil {
+						logger.Log("msg", "Cannot reload certificate; keeping the previous one", "cert", *certFile, "key", *keyFile, "err", err)
+					}
+				}
+				if *policy != "" {
+					if err := tap.ReloadPolicy(*policy); err != nil {
+						logger.Log("msg", "Cannot reload rate policy; keeping the previous one", "policy", *policy, "err", err)
+					} else {
+						logger.Log("msg", "Rate policy reloaded", "policy", *policy)
+					}
+				}
+				if *access != "" {
+					p, err := LoadAccessPolicy(*access)
+					if err != nil {
+						logger.Log("msg", "Cannot reload access policy; keeping the previous one", "access", *access, "err", err)
+					} else {
+						authz.SetPolicy(p)
+						logger.Log("msg", "Access policy reloaded", "access", *access)
+					}
+				}
+			}
+		}()
+	}
 
 	// Wait for Ctrl+C and other signals
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		<-c
-		errc <- nil
-	}()
+	sigc := make(chan os.Signal, 2)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
 
 	// Log all settings for debugging purposes
 	logger.Log(
 		"msg", "Server started",
 		"addr", *addr,
+		"adminAddr", *adminAddr,
 		"disco", *disco,
 		"tls", *tls,
+		"tlsProtocols", tlsProtocols,
+		"plaintextProtocols", plainProtocols,
 		"certFile", *certFile,
 		"keyFile", *keyFile,
+		"certReloadInterval", *certPoll,
+		"grpcWebOrigins", *origins,
+		"clientCA", *clientCA,
 		"qps", *qps,
 		"burst", *burst,
+		"auth", *auth,
+		"tokens", *tokens,
+		"jwtKey", *jwtKey,
+		"jwtIssuer", *jwtIss,
+		"jwtAudience", *jwtAud,
+		"policy", *policy,
+		"access", *access,
+		"limiter", *limiter,
+		"redisAddr", *redisAddr,
+		"limiterMaxUsers", *maxUsers,
+		"limiterTTL", *idleTTL,
+		"limiterShards", *shards,
+		"maxCallsPerUser", *userCalls,
+		"maxStreamsPerUser", *userStrms,
+		"reflection", *reflect,
+		"htpasswd", *htpasswd,
+		"drainGrace", *grace,
+		"drainTimeout", *timeout,
+		"keepaliveTime", *kaTime,
+		"keepaliveTimeout", *kaTimeout,
+		"keepaliveMinTime", *kaMinTime,
+		"maxConnectionAge", *connAge,
+		"maxConnectionAgeGrace", *connGrace,
+		"maxConcurrentStreams", *maxStrms,
 	)
 	defer logger.Log("msg", "Server stopped")
 
 	// Wait for completion
-	if err := <-errc; err != nil {
-		logger.Log("msg", "Exit with failure", "err", err)
+	select {
+	case err := <-errc:
+		if err != nil {
+			logger.Log("msg", "Exit with failure", "err", err)
+		}
+	case sig := <-sigc:
+		logger.Log("msg", "Shutting down", "signal", sig)
+		// A second signal stops the server immediately
+		force := make(chan struct{})
+		go func() {
+			<-sigc
+			close(force)
+		}()
+		shutdown.Run(force)
 	}
 }
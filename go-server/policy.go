@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Limit is a rate limit in queries per second, with a burst.
+type Limit struct {
+	QPS   float64 `json:"qps"`
+	Burst int     `json:"burst"`
+}
+
+// RatePolicy describes the rate limits per method and per user tier.
+// It is usually loaded from a JSON file like this:
+//
+//	{
+//	  "default": {"qps": 5, "burst": 1},
+//	  "methods": {
+//	    "/com.altf4.grpc.Example/Ticker": {"qps": 0.1, "burst": 2}
+//	  },
+//	  "tiers": {
+//	    "gold": {
+//	      "default": {"qps": 100, "burst": 20},
+//	      "methods": {
+//	        "/com.altf4.grpc.Example/Ticker": {"qps": 1, "burst": 5}
+//	      }
+//	    }
+//	  },
+//	  "users": {"alice": "gold"}
+//	}
+//
+// The limit for a call is looked up in this order: the method in the
+// tier of the user, the default of the tier, the method, and finally
+// the default. Methods are identified by their full name, e.g.
+// "/com.altf4.grpc.Example/Hello".
+type RatePolicy struct {
+	Default *Limit                `json:"default,omitempty"`
+	Methods map[string]Limit      `json:"methods,omitempty"`
+	Tiers   map[string]TierPolicy `json:"tiers,omitempty"`
+	Users   map[string]string     `json:"users,omitempty"`
+}
+
+// TierPolicy describes the rate limits for all users of a tier.
+type TierPolicy struct {
+	Default *Limit           `json:"default,omitempty"`
+	Methods map[string]Limit `json:"methods,omitempty"`
+}
+
+// LoadRatePolicy reads a rate policy from a JSON file.
+func LoadRatePolicy(filename string) (*RatePolicy, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	policy := new(RatePolicy)
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(policy); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", filename, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy in %s: %v", filename, err)
+	}
+	return policy, nil
+}
+
+// Validate checks the policy for errors.
+func (p *RatePolicy) Validate() error {
+	if p.Default == nil {
+		return fmt.Errorf("missing default limit")
+	}
+	if err := p.Default.validate(); err != nil {
+		return fmt.Errorf("default: %v", err)
+	}
+	for method, limit := range p.Methods {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("method %s: %v", method, err)
+		}
+	}
+	for name, tier := range p.Tiers {
+		if tier.Default != nil {
+			if err := tier.Default.validate(); err != nil {
+				return fmt.Errorf("tier %s: default: %v", name, err)
+			}
+		}
+		for method, limit := range tier.Methods {
+			if err := limit.validate(); err != nil {
+				return fmt.Errorf("tier %s: method %s: %v", name, method, err)
+			}
+		}
+	}
+	for user, tier := range p.Users {
+		if _, found := p.Tiers[tier]; !found {
+			return fmt.Errorf("user %s: unknown tier %q", user, tier)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the limit for a call of user to method. It also returns
+// the scope of the limit: calls with the same user and scope share a
+// rate limiter. Limits that apply to a specific method have that method
+// as scope, defaults share a common scope across methods.
+func (p *RatePolicy) Lookup(user, method string) (Limit, string) {
+	if tier, found := p.Tiers[p.Users[user]]; found {
+		name := p.Users[user]
+		if limit, found := tier.Methods[method]; found {
+			return limit, name + ":" + method
+		}
+		if tier.Default != nil {
+			return *tier.Default, name + ":*"
+		}
+	}
+	if limit, found := p.Methods[method]; found {
+		return limit, method
+	}
+	return *p.Default, "*"
+}
+
+func (l Limit) validate() error {
+	if math.IsNaN(l.QPS) || math.IsInf(l.QPS, 0) {
+		return fmt.Errorf("qps must be a finite number")
+	}
+	if l.QPS < 0 {
+		return fmt.Errorf("qps must not be negative")
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("burst must not be negative")
+	}
+	return nil
+}
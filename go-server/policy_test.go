@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	testHelloMethod  = "/com.altf4.grpc.Example/Hello"
+	testTickerMethod = "/com.altf4.grpc.Example/Ticker"
+)
+
+// testRatePolicy has limits on all four levels of the lookup.
+const testRatePolicy = `{
+  "default": {"qps": 5, "burst": 1},
+  "methods": {
+    "/com.altf4.grpc.Example/Ticker": {"qps": 0.1, "burst": 2}
+  },
+  "tiers": {
+    "gold": {
+      "default": {"qps": 100, "burst": 20},
+      "methods": {
+        "/com.altf4.grpc.Example/Ticker": {"qps": 1, "burst": 5}
+      }
+    },
+    "silver": {
+      "methods": {
+        "/com.altf4.grpc.Example/Ticker": {"qps": 0.5, "burst": 3}
+      }
+    }
+  },
+  "users": {"alice": "gold", "carol": "silver"}
+}`
+
+// writePolicy writes data into a file in dir and returns its name.
+func writePolicy(t *testing.T, dir, name, data string) string {
+	filename := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(filename, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestRatePolicyLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc-demo-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	policy, err := LoadRatePolicy(writePolicy(t, dir, "policy.json", testRatePolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		user   string
+		method string
+		limit  Limit
+		scope  string
+	}{
+		// Method in the tier of the user
+		{"alice", testTickerMethod, Limit{QPS: 1, Burst: 5}, "gold:" + testTickerMethod},
+		// Default of the tier
+		{"alice", testHelloMethod, Limit{QPS: 100, Burst: 20}, "gold:*"},
+		// Method, for users without a tier and tiers without a default
+		{"bob", testTickerMethod, Limit{QPS: 0.1, Burst: 2}, testTickerMethod},
+		{"carol", testTickerMethod, Limit{QPS: 0.5, Burst: 3}, "silver:" + testTickerMethod},
+		// Default
+		{"bob", testHelloMethod, Limit{QPS: 5, Burst: 1}, "*"},
+		{"carol", testHelloMethod, Limit{QPS: 5, Burst: 1}, "*"},
+	}
+	for _, tt := range tests {
+		limit, scope := policy.Lookup(tt.user, tt.method)
+		if want, have := tt.limit, limit; want != have {
+			t.Errorf("%s %s: expected limit %+v, have %+v", tt.user, tt.method, want, have)
+		}
+		if want, have := tt.scope, scope; want != have {
+			t.Errorf("%s %s: expected scope %q, have %q", tt.user, tt.method, want, have)
+		}
+	}
+}
+
+func TestTapHandlerReloadPolicyKeepsPolicyOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc-demo-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	policy, err := LoadRatePolicy(writePolicy(t, dir, "policy.json", testRatePolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewTapHandler(log.NewNopLogger(), NewMetrics(), nil, nil, policy)
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid JSON", `{"default": {"qps": 5,`},
+		{"missing default", `{"methods": {}}`},
+		{"negative qps", `{"default": {"qps": -1, "burst": 1}}`},
+		{"negative burst", `{"default": {"qps": 1, "burst": -1}}`},
+		{"unknown tier", `{"default": {"qps": 1, "burst": 1}, "users": {"alice": "platinum"}}`},
+	}
+	for _, tt := range tests {
+		filename := writePolicy(t, dir, "invalid.json", tt.data)
+		if err := h.ReloadPolicy(filename); err == nil {
+			t.Errorf("%s: expected the policy to be rejected", tt.name)
+		}
+		if h.policy != policy {
+			t.Errorf("%s: expected the previous policy to be kept", tt.name)
+		}
+	}
+	if err := h.ReloadPolicy(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected a missing policy file to be rejected")
+	}
+	if h.policy != policy {
+		t.Error("expected the previous policy to be kept for a missing file")
+	}
+
+	filename := writePolicy(t, dir, "valid.json", `{"default": {"qps": 1, "burst": 1}}`)
+	if err := h.ReloadPolicy(filename); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := (Limit{QPS: 1, Burst: 1}), *h.policy.Default; want != have {
+		t.Errorf("expected reloaded default %+v, have %+v", want, have)
+	}
+}
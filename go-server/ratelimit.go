@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// RateLimiter decides whether a call may proceed. Implementations may
+// keep their state in memory, or share it with other servers so that
+// a limit holds across the whole cluster.
+type RateLimiter interface {
+	// Allow takes one call for key from the quota, given limit.
+	// Calls with the same key share a quota.
+	Allow(ctx context.Context, key string, limit Limit) (Quota, error)
+}
+
+// Quota is the result of asking a RateLimiter for a call.
+type Quota struct {
+	// Allowed is true if the call may proceed.
+	Allowed bool
+	// Remaining is the number of calls that may follow immediately.
+	Remaining int
+	// ResetAfter is the time until the quota is fully replenished.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next call will be allowed.
+	// It is zero if Remaining is greater than zero.
+	RetryAfter time.Duration
+}
+
+// minInterval is the shortest emission interval, i.e. 1/QPS, that the
+// rate limiters enforce. redisRateLimiter keeps times in microseconds,
+// so shorter intervals would be rounded to zero. Limits with a shorter
+// interval, or an infinite QPS, let all calls through.
+const minInterval = time.Microsecond
+
+// unlimited returns true if l lets all calls through, because its
+// QPS is infinite or its emission interval is shorter than minInterval.
+func (l Limit) unlimited() bool {
+	return math.IsInf(l.QPS, 1) || l.QPS > float64(time.Second/minInterval)
+}
+
+// unlimitedQuota is the quota of a call with an unlimited limit.
+func unlimitedQuota(limit Limit) Quota {
+	return Quota{Allowed: true, Remaining: limit.Burst}
+}
+
+// memoryRateLimiter enforces limits per server, with a bucket
+// per key kept in a LimiterStore.
+type memoryRateLimiter struct {
+	store *LimiterStore
+	now   func() time.Time
+}
+
+// NewMemoryRateLimiter returns a RateLimiter that keeps its state in
+// memory. When running several servers, each one enforces the limit
+// on its own.
+func NewMemoryRateLimiter(store *LimiterStore) RateLimiter {
+	return &memoryRateLimiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow implements the RateLimiter interface.
+func (l *memoryRateLimiter) Allow(ctx context.Context, key string, limit Limit) (Quota, error) {
+	return l.store.Get(key).take(l.now(), limit), nil
+}
+
+// bucket is a token bucket, implemented with the generic cell rate
+// algorithm (GCRA). Instead of counting tokens, it only keeps the
+// theoretical arrival time (TAT) of the next call: a call is allowed
+// if it doesn't arrive earlier than burst emission intervals before
+// the TAT. redisRateLimiterScript implements the same algorithm.
+type bucket struct {
+	mu  sync.Mutex
+	tat time.Time
+}
+
+// take takes one call from the bucket at time now.
+func (b *bucket) take(now time.Time, limit Limit) Quota {
+	if limit.unlimited() {
+		return unlimitedQuota(limit)
+	}
+	if limit.QPS <= 0 || limit.Burst <= 0 {
+		return Quota{}
+	}
+	interval := time.Duration(float64(time.Second) / limit.QPS)
+	tolerance := time.Duration(limit.Burst) * interval
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tat := b.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newtat := tat.Add(interval)
+	if newtat.Sub(now) > tolerance {
+		return Quota{
+			Allowed:    false,
+			Remaining:  0,
+			ResetAfter: tat.Sub(now),
+			RetryAfter: newtat.Sub(now) - tolerance,
+		}
+	}
+	b.tat = newtat
+
+	quota := Quota{
+		Allowed:    true,
+		Remaining:  int((tolerance - newtat.Sub(now)) / interval),
+		ResetAfter: newtat.Sub(now),
+	}
+	if quota.Remaining == 0 {
+		quota.RetryAfter = newtat.Sub(now) + interval - tolerance
+	}
+	return quota
+}
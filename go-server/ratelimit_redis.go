@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"golang.org/x/net/context"
+)
+
+// redisRateLimiterScript implements the generic cell rate algorithm
+// (GCRA), just like bucket does in memory. It only needs to store a
+// single value per key: the theoretical arrival time (TAT) of the next
+// call. All times are in microseconds.
+//
+// KEYS[1] is the key, ARGV[1] is the current time, ARGV[2] is the
+// emission interval (1/qps), and ARGV[3] is the burst.
+// It returns {allowed (0 or 1), remaining, reset after, retry after}.
+var redisRateLimiterScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local tolerance = burst * interval
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if tat == nil or tat < now then
+  tat = now
+end
+
+local newtat = tat + interval
+if newtat - now > tolerance then
+  return {0, 0, tat - now, newtat - now - tolerance}
+end
+
+redis.call("SET", KEYS[1], newtat, "PX", math.max(1, math.ceil((newtat - now) / 1000)))
+
+local remaining = math.floor((tolerance - (newtat - now)) / interval)
+local retry = 0
+if remaining == 0 then
+  retry = newtat - now + interval - tolerance
+end
+return {1, remaining, newtat - now, retry}
+`)
+
+// redisRateLimiter enforces limits across all servers that share
+// the same Redis server (or anything that speaks its protocol).
+type redisRateLimiter struct {
+	client *redis.Client
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisRateLimiter returns a RateLimiter that keeps its state in Redis,
+// so a limit holds across all servers that use the same Redis server.
+// Keys are prefixed with prefix.
+//
+// Servers should have their clocks synchronized, e.g. via NTP.
+func NewRedisRateLimiter(client *redis.Client, prefix string) RateLimiter {
+	return &redisRateLimiter{
+		client: client,
+		prefix: prefix,
+		now:    time.Now,
+	}
+}
+
+// Allow implements the RateLimiter interface.
+func (l *redisRateLimiter) Allow(ctx context.Context, key string, limit Limit) (Quota, error) {
+	if limit.unlimited() {
+		return unlimitedQuota(limit), nil
+	}
+	if limit.QPS <= 0 || limit.Burst <= 0 {
+		return Quota{}, nil
+	}
+	now := l.now().UnixNano() / int64(time.Microsecond)
+	interval := int64(float64(time.Second/time.Microsecond) / limit.QPS)
+	res, err := redisRateLimiterScript.Run(
+		l.client.WithContext(ctx),
+		[]string{l.prefix + key},
+		now,
+		interval,
+		limit.Burst,
+	).Result()
+	if err != nil {
+		return Quota{}, err
+	}
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 4 {
+		return Quota{}, fmt.Errorf("unexpected result from rate limiter script: %v", res)
+	}
+	var ints [4]int64
+	for i, v := range values {
+		if ints[i], ok = v.(int64); !ok {
+			return Quota{}, fmt.Errorf("unexpected result from rate limiter script: %v", res)
+		}
+	}
+	return Quota{
+		Allowed:    ints[0] == 1,
+		Remaining:  int(ints[1]),
+		ResetAfter: time.Duration(ints[2]) * time.Microsecond,
+		RetryAfter: time.Duration(ints[3]) * time.Microsecond,
+	}, nil
+}
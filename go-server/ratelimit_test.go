@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBucketAllowsAllCallsWithUnlimitedQPS(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Limit
+	}{
+		{"infinite", Limit{QPS: math.Inf(1), Burst: 1}},
+		{"infinite without burst", Limit{QPS: math.Inf(1), Burst: 0}},
+		{"interval below a nanosecond", Limit{QPS: 2e9, Burst: 1}},
+		{"interval below a microsecond", Limit{QPS: 2e6, Burst: 1}},
+	}
+	for _, tt := range tests {
+		b := new(bucket)
+		now := time.Now()
+		for i := 0; i < 100; i++ {
+			quota := b.take(now, tt.limit)
+			if !quota.Allowed {
+				t.Fatalf("%s: expected call %d to be allowed", tt.name, i+1)
+			}
+			if quota.RetryAfter != 0 {
+				t.Errorf("%s: expected no retry delay, have %v", tt.name, quota.RetryAfter)
+			}
+		}
+	}
+}
+
+func TestBucketLimitsCallsWithHighQPS(t *testing.T) {
+	limit := Limit{QPS: 1e6, Burst: 2}
+	b := new(bucket)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if quota := b.take(now, limit); !quota.Allowed {
+			t.Fatalf("expected call %d to be allowed", i+1)
+		}
+	}
+	quota := b.take(now, limit)
+	if quota.Allowed {
+		t.Fatal("expected call 3 to be rejected")
+	}
+	if want, have := time.Microsecond, quota.RetryAfter; want != have {
+		t.Errorf("expected retry delay %v, have %v", want, have)
+	}
+	if quota := b.take(now.Add(time.Microsecond), limit); !quota.Allowed {
+		t.Error("expected call to be allowed after the retry delay")
+	}
+}
+
+func TestLimitValidateRejectsNonFiniteQPS(t *testing.T) {
+	for _, qps := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+		if err := (Limit{QPS: qps, Burst: 1}).validate(); err == nil {
+			t.Errorf("expected qps=%v to be rejected", qps)
+		}
+	}
+}
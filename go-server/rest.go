@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/tap"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+const (
+	helloMethod  = "/com.altf4.grpc.Example/Hello"
+	tickerMethod = "/com.altf4.grpc.Example/Ticker"
+
+	// restDefaultTimeout is the deadline of unary REST calls that
+	// don't pass a timeout.
+	restDefaultTimeout = 10 * time.Second
+)
+
+// restHandler serves the Example service as REST/JSON, transcoding
+// requests and responses to and from the protobuf messages:
+//
+//	POST /v1/hello   with a HelloRequest, returns a HelloResponse
+//	GET  /v1/ticker  with the fields of a TickerRequest as query parameters,
+//	                 streams TickerResponses as server-sent events if the client
+//	                 accepts text/event-stream, or as newline-delimited JSON otherwise
+//
+// Calls go through the same tap handler and interceptors as gRPC calls,
+// i.e. they are authenticated, authorized and rate limited just the same.
+// Credentials are passed in the Authorization header.
+type restHandler struct {
+	logger    log.Logger
+	srv       pb.ExampleServer
+	tap       tap.ServerInHandle
+	unary     grpc.UnaryServerInterceptor
+	stream    grpc.StreamServerInterceptor
+	marshaler *jsonpb.Marshaler
+}
+
+// newRESTHandler creates a new REST handler for srv. tapHandle, unary and
+// stream are the tap handler and interceptors that the gRPC server uses.
+func newRESTHandler(logger log.Logger, srv pb.ExampleServer, tapHandle tap.ServerInHandle, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) *restHandler {
+	return &restHandler{
+		logger:    log.With(logger, "component", "rest"),
+		srv:       srv,
+		tap:       tapHandle,
+		unary:     unary,
+		stream:    stream,
+		marshaler: &jsonpb.Marshaler{EmitDefaults: true},
+	}
+}
+
+// Register the REST endpoints with r.
+func (h *restHandler) Register(r *mux.Router) {
+	s := r.PathPrefix("/v1").Subrouter()
+	s.HandleFunc("/hello", h.hello).Methods("POST")
+	s.HandleFunc("/ticker", h.ticker).Methods("GET")
+}
+
+// hello transcodes a Hello call. The deadline of the call can be passed
+// as a duration in the timeout query parameter, e.g. "timeout=10s".
+func (h *restHandler) hello(w http.ResponseWriter, r *http.Request) {
+	req := new(pb.HelloRequest)
+	if err := jsonpb.Unmarshal(r.Body, req); err != nil && err != io.EOF {
+		h.writeError(w, status.Errorf(codes.InvalidArgument, "invalid request: %v", err))
+		return
+	}
+	timeout := restDefaultTimeout
+	if s := r.URL.Query().Get("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			h.writeError(w, status.Errorf(codes.InvalidArgument, "invalid timeout: %v", err))
+			return
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	ctx, ts, err := h.newContext(ctx, r, helloMethod)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	info := &grpc.UnaryServerInfo{Server: h.srv, FullMethod: helloMethod}
+	res, err := h.unary(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return h.srv.Hello(ctx, req.(*pb.HelloRequest))
+	})
+	ts.copyTo(w.Header())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.marshaler.Marshal(w, res.(proto.Message)); err != nil {
+		h.logger.Log("msg", "Cannot write response", "method", helloMethod, "err", err)
+	}
+}
+
+// ticker transcodes a Ticker call. The interval can be passed as a
+// duration, e.g. "interval=1s", or in nanoseconds, and the end as
+// RFC3339 or in nanoseconds since the epoch. Server-sent events carry
+// the resume token as their ID, so browsers resume interrupted streams
+// via the Last-Event-ID header.
+func (h *restHandler) ticker(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := &pb.TickerRequest{
+		Timezone:    q.Get("timezone"),
+		ResumeToken: q.Get("resume_token"),
+	}
+	if req.ResumeToken == "" {
+		req.ResumeToken = r.Header.Get("Last-Event-ID")
+	}
+	if s := q.Get("max_count"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			h.writeError(w, status.Errorf(codes.InvalidArgument, "invalid max_count: %v", err))
+			return
+		}
+		req.MaxCount = n
+	}
+	if s := q.Get("end"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			n, nerr := strconv.ParseInt(s, 10, 64)
+			if nerr != nil {
+				h.writeError(w, status.Errorf(codes.InvalidArgument, "invalid end: %v", err))
+				return
+			}
+			t = time.Unix(0, n)
+		}
+		req.End = t.UnixNano()
+	}
+	if s := q.Get("interval"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			n, nerr := strconv.ParseInt(s, 10, 64)
+			if nerr != nil {
+				h.writeError(w, status.Errorf(codes.InvalidArgument, "invalid interval: %v", err))
+				return
+			}
+			d = time.Duration(n)
+		}
+		req.Interval = d.Nanoseconds()
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeError(w, status.Error(codes.Unimplemented, "streaming is not supported"))
+		return
+	}
+
+	ctx, ts, err := h.newContext(r.Context(), r, tickerMethod)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	stream := &restServerStream{
+		ctx:     ctx,
+		ts:      ts,
+		w:       w,
+		flusher: flusher,
+		sse:     strings.Contains(r.Header.Get("Accept"), "text/event-stream"),
+		h:       h,
+	}
+	info := &grpc.StreamServerInfo{FullMethod: tickerMethod, IsServerStream: true}
+	err = h.stream(h.srv, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return srv.(pb.ExampleServer).Ticker(req, &restTickerServer{stream})
+	})
+	switch {
+	case err == nil || ctx.Err() != nil:
+	case !stream.started():
+		// Nothing sent yet, so we can still respond with an error status
+		ts.copyTo(w.Header())
+		h.writeError(w, err)
+	default:
+		stream.sendError(err)
+	}
+}
+
+// newContext prepares the context of a call to method, with a transport
+// stream that collects headers and trailers, and runs the tap handler.
+func (h *restHandler) newContext(ctx context.Context, r *http.Request, method string) (context.Context, *restTransportStream, error) {
+	ts := &restTransportStream{method: method}
+	ctx = grpc.NewContextWithServerTransportStream(ctx, ts)
+	ctx, err := tapHTTPRequest(ctx, h.tap, r, method)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, ts, nil
+}
+
+// writeError writes err as JSON, with the HTTP status that corresponds
+// to its gRPC code.
+func (h *restHandler) writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	if d := retryDelay(st); d > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((d+time.Second-1)/time.Second)))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFromCode(st.Code()))
+	if err := h.marshaler.Marshal(w, st.Proto()); err != nil {
+		h.logger.Log("msg", "Cannot write error", "err", err)
+	}
+}
+
+// tapHTTPRequest runs handle for a call to method that came in via HTTP
+// as r, e.g. via REST or gRPC-Web. Like the gRPC transport, it passes
+// the credentials of r as metadata and the peer in ctx.
+func tapHTTPRequest(ctx context.Context, handle tap.ServerInHandle, r *http.Request, method string) (context.Context, error) {
+	md := metadata.MD{}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		md["authorization"] = []string{auth}
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	p := &peer.Peer{Addr: httpAddr(r.RemoteAddr)}
+	if info, ok := r.Context().Value(connAuthInfoKey).(credentials.AuthInfo); ok {
+		p.AuthInfo = info
+	}
+	ctx = peer.NewContext(ctx, p)
+
+	return handle(ctx, &tap.Info{FullMethodName: method})
+}
+
+// withConnAuthInfo is used as http.Server.ConnContext. It makes the TLS
+// state of the connection available to the REST handler. We terminate
+// TLS in front of cmux, so http.Request.TLS is always nil.
+func withConnAuthInfo(ctx context.Context, conn net.Conn) context.Context {
+	_, info, err := tlsInfoCredentials{}.ServerHandshake(conn)
+	if err != nil || info == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, connAuthInfoKey, info)
+}
+
+// retryDelay returns the delay in the RetryInfo detail of st, if any.
+func retryDelay(st *status.Status) time.Duration {
+	for _, detail := range st.Details() {
+		if ri, ok := detail.(*errdetails.RetryInfo); ok && ri.RetryDelay != nil {
+			if d, err := ptypes.Duration(ri.RetryDelay); err == nil {
+				return d
+			}
+		}
+	}
+	return 0
+}
+
+// httpStatusFromCode returns the HTTP status for a gRPC code.
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // client closed request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// httpAddr is the address of an HTTP client.
+type httpAddr string
+
+func (a httpAddr) Network() string { return "tcp" }
+func (a httpAddr) String() string  { return string(a) }
+
+// restTransportStream collects the headers and trailers that handlers
+// and interceptors set, e.g. via grpc.SetTrailer. It implements
+// grpc.ServerTransportStream.
+type restTransportStream struct {
+	method string
+
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *restTransportStream) Method() string { return s.method }
+
+func (s *restTransportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	s.header = metadata.Join(s.header, md)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *restTransportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *restTransportStream) SetTrailer(md metadata.MD) error {
+	s.mu.Lock()
+	s.trailer = metadata.Join(s.trailer, md)
+	s.mu.Unlock()
+	return nil
+}
+
+// copyTo copies headers and trailers into HTTP headers. As HTTP clients
+// rarely look at trailers, we send trailers as headers as well.
+func (s *restTransportStream) copyTo(header http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, md := range []metadata.MD{s.header, s.trailer} {
+		for k, values := range md {
+			if strings.HasSuffix(k, "-bin") {
+				continue
+			}
+			k = textproto.CanonicalMIMEHeaderKey(k)
+			for _, v := range values {
+				header.Add(k, v)
+			}
+		}
+	}
+}
+
+// restServerStream implements grpc.ServerStream on top of an HTTP response.
+// Messages are sent as server-sent events or as newline-delimited JSON.
+type restServerStream struct {
+	ctx     context.Context
+	ts      *restTransportStream
+	w       http.ResponseWriter
+	flusher http.Flusher
+	sse     bool
+	h       *restHandler
+
+	mu          sync.Mutex
+	wroteHeader bool
+}
+
+func (s *restServerStream) Context() context.Context        { return s.ctx }
+func (s *restServerStream) SetHeader(md metadata.MD) error  { return s.ts.SetHeader(md) }
+func (s *restServerStream) SendHeader(md metadata.MD) error { return s.ts.SendHeader(md) }
+func (s *restServerStream) SetTrailer(md metadata.MD)       { s.ts.SetTrailer(md) }
+func (s *restServerStream) RecvMsg(m interface{}) error     { return io.EOF }
+func (s *restServerStream) SendMsg(m interface{}) error     { return s.send("result", m.(proto.Message)) }
+func (s *restServerStream) sendError(err error)             { s.send("error", status.Convert(err).Proto()) }
+func (s *restServerStream) contentType() string {
+	if s.sse {
+		return "text/event-stream"
+	}
+	return "application/x-ndjson"
+}
+
+// started returns true if the response has been started.
+func (s *restServerStream) started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wroteHeader
+}
+
+// send writes m as an event of the given kind, i.e. "result" or "error".
+func (s *restServerStream) send(kind string, m proto.Message) error {
+	var buf bytes.Buffer
+	if err := s.h.marshaler.Marshal(&buf, m); err != nil {
+		return status.Errorf(codes.Internal, "cannot marshal %s: %v", kind, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.wroteHeader {
+		s.ts.copyTo(s.w.Header())
+		s.w.Header().Set("Content-Type", s.contentType())
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+		s.wroteHeader = true
+	}
+	var err error
+	if s.sse {
+		if kind == "error" {
+			_, err = fmt.Fprintf(s.w, "event: error\ndata: %s\n\n", buf.Bytes())
+		} else if t, ok := m.(interface{ GetResumeToken() string }); ok && t.GetResumeToken() != "" {
+			_, err = fmt.Fprintf(s.w, "id: %s\ndata: %s\n\n", t.GetResumeToken(), buf.Bytes())
+		} else {
+			_, err = fmt.Fprintf(s.w, "data: %s\n\n", buf.Bytes())
+		}
+	} else {
+		_, err = fmt.Fprintf(s.w, "{%q:%s}\n", kind, buf.Bytes())
+	}
+	if err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// restTickerServer implements pb.Example_TickerServer.
+type restTickerServer struct {
+	grpc.ServerStream
+}
+
+func (s *restTickerServer) Send(m *pb.TickerResponse) error {
+	return s.ServerStream.SendMsg(m)
+}
@@ -4,6 +4,8 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -14,34 +16,65 @@ import (
 	pb "github.com/olivere/grpc-demo/pb"
 )
 
+// maxHelloMany is the maximum number of requests in a HelloMany call.
+const maxHelloMany = 100
+
 type Server struct {
 	log.Logger
+	rooms     *chatRooms
+	tickerKey []byte // signs Ticker resume tokens
+	draining  chan struct{}
+	drainOnce sync.Once
 }
 
-func NewServer(logger log.Logger) *Server {
+// NewServer creates the server. Ticker resume tokens are signed with
+// tickerKey; all servers that clients may resume streams on must use
+// the same key.
+func NewServer(logger log.Logger, tickerKey []byte) *Server {
 	return &Server{
-		Logger: log.With(logger, "component", "server"),
+		Logger:    log.With(logger, "component", "server"),
+		rooms:     newChatRooms(),
+		tickerKey: tickerKey,
+		draining:  make(chan struct{}),
 	}
 }
 
+// Drain ends the endless Ticker and Chat streams with Unavailable, so
+// their clients resume them on another server. It is called when the
+// server shuts down.
+func (s *Server) Drain() {
+	s.drainOnce.Do(func() { close(s.draining) })
+}
+
+// errDraining is returned from streams that end because of Drain.
+var errDraining = status.Error(codes.Unavailable, "server is shutting down; please reconnect")
+
 func (s *Server) Hello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloResponse, error) {
-	user, ok := getUser(ctx)
+	id, ok := getIdentity(ctx)
 	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "client didn't pass a user")
+		return nil, status.Error(codes.Unauthenticated, "request is not authenticated")
 	}
-	s.Log("method", "Hello", "user", user)
+	s.Log("method", "Hello", "user", id.Subject)
+	now := time.Now()
 
 	d, ok := ctx.Deadline()
 	if !ok {
 		return nil, status.Error(codes.InvalidArgument,
 			"no deadline/timeout specified in request")
 	}
-	timeout := d.Sub(time.Now())
+	timeout := d.Sub(now)
 	if timeout < 5*time.Second || timeout >= 30*time.Second {
 		return nil, status.Errorf(codes.InvalidArgument,
 			"deadline must be 5-30 seconds in future; was: %v", timeout)
 	}
+	if err := validateHelloRequest(req, now); err != nil {
+		return nil, err
+	}
+	return hello(req, now), nil
+}
 
+// hello returns the response to req, received at now.
+func hello(req *pb.HelloRequest, now time.Time) *pb.HelloResponse {
 	var gender string
 	switch req.Gender {
 	case pb.Gender_MALE:
@@ -52,41 +85,197 @@ func (s *Server) Hello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloResp
 		gender = "person of an unknown gender"
 	}
 	msg := fmt.Sprintf("%s: Hello %s, you are a %d year old %s.",
-		time.Now().Format(time.RFC3339),
+		now.Format(time.RFC3339),
 		req.Name,
 		req.Age,
 		gender,
 	)
+	var latency time.Duration
+	if req.Nanos > 0 {
+		latency = now.Sub(time.Unix(0, req.Nanos))
+	}
 	return &pb.HelloResponse{
-		Message: msg,
-	}, nil
+		Message:    msg,
+		Latency:    int64(latency),
+		Tags:       req.Tags,
+		Properties: req.Properties,
+		Online:     req.Online,
+	}
 }
 
 func (s *Server) Ticker(req *pb.TickerRequest, stream pb.Example_TickerServer) error {
 	ctx := stream.Context()
 
-	user, ok := getUser(ctx)
+	id, ok := getIdentity(ctx)
 	if !ok {
-		return status.Error(codes.Unauthenticated, "client didn't pass a user")
+		return status.Error(codes.Unauthenticated, "request is not authenticated")
 	}
-	s.Log("method", "Ticker", "user", user)
+	s.Log("method", "Ticker", "user", id.Subject)
 
 	loc, err := time.LoadLocation(req.Timezone)
 	if err != nil {
 		return status.Errorf(codes.InvalidArgument, "invalid timezone")
 	}
-	ticker := time.NewTicker(time.Duration(req.Interval) * time.Nanosecond)
-	defer ticker.Stop()
+	now := time.Now()
+	if err := validateTickerRequest(req, s.tickerKey, now); err != nil {
+		return err
+	}
+
+	// Continue the schedule of the resumed stream, if any, and skip
+	// the ticks that were missed in the meantime
+	interval := time.Duration(req.Interval)
+	token := tickerToken{Start: now.UnixNano(), Interval: req.Interval}
+	if req.ResumeToken != "" {
+		token, _ = parseTickerToken(req.ResumeToken, s.tickerKey) // checked above
+	}
+	start := time.Unix(0, token.Start)
+	seq := token.Sequence
+	if missed := int64(now.Sub(start) / interval); missed > seq {
+		seq = missed
+	}
 
 	for {
+		seq++
+		if req.MaxCount > 0 && seq > req.MaxCount {
+			return nil
+		}
+		at := start.Add(time.Duration(seq) * interval)
+		last := req.End > 0 && at.UnixNano() > req.End
+		if last {
+			at = time.Unix(0, req.End)
+		}
+		timer := time.NewTimer(at.Sub(time.Now()))
 		select {
-		case <-ticker.C:
+		case t := <-timer.C:
+			if last {
+				return nil
+			}
+			token.Sequence = seq
 			err := stream.Send(&pb.TickerResponse{
-				Tick: time.Now().In(loc).Format(time.RFC3339),
+				Tick:        t.In(loc).Format(time.RFC3339),
+				Sequence:    seq,
+				Nanos:       t.UnixNano(),
+				ResumeToken: token.Sign(s.tickerKey),
 			})
 			if err != nil {
 				return err
 			}
+		case <-s.draining:
+			timer.Stop()
+			return errDraining
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Server) HelloMany(stream pb.Example_HelloManyServer) error {
+	ctx := stream.Context()
+
+	id, ok := getIdentity(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "request is not authenticated")
+	}
+	s.Log("method", "HelloMany", "user", id.Subject)
+
+	res := new(pb.HelloManyResponse)
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(res)
+		}
+		if err != nil {
+			return err
+		}
+		n := len(res.Responses) + 1
+		if n > maxHelloMany {
+			return status.Errorf(codes.InvalidArgument,
+				"at most %d requests are allowed per call", maxHelloMany)
+		}
+		now := time.Now()
+		if err := helloRequestViolations(req, now).err(fmt.Sprintf("invalid request #%d", n)); err != nil {
+			return err
+		}
+		res.Responses = append(res.Responses, hello(req, now))
+	}
+}
+
+func (s *Server) Chat(stream pb.Example_ChatServer) error {
+	ctx := stream.Context()
+
+	id, ok := getIdentity(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "request is not authenticated")
+	}
+
+	// The first message joins the room
+	msg, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := validateChatMessage(msg, ""); err != nil {
+		return err
+	}
+	room := msg.Room
+	s.Log("method", "Chat", "user", id.Subject, "room", room)
+
+	m := s.rooms.join(room, id.Subject)
+	defer s.rooms.leave(m)
+	if msg.Text != "" {
+		s.rooms.send(room, id.Subject, msg.Text)
+	}
+
+	// Receive messages from the client until it closes its side
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				recvErr <- nil
+				return
+			}
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			if err := validateChatMessage(msg, room); err != nil {
+				recvErr <- err
+				return
+			}
+			s.rooms.send(room, id.Subject, msg.Text)
+		}
+	}()
+
+	// Send the messages of the room to the client
+	for {
+		select {
+		case msg := <-m.out:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case <-m.gone:
+			return status.Error(codes.ResourceExhausted, "client is too slow to receive messages")
+		case <-s.draining:
+			return errDraining
+		case err := <-recvErr:
+			if err != nil {
+				return err
+			}
+			// The client left; send what is pending, e.g. its own messages
+			for {
+				select {
+				case msg := <-m.out:
+					if err := stream.Send(msg); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
 		case <-ctx.Done():
 			return ctx.Err()
 		}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"google.golang.org/grpc"
+
+	"github.com/olivere/grpc-demo/go-server/health"
+)
+
+// shutdownSequence stops the server gracefully, in this order:
+//
+//  1. Mark the server as draining, which makes it unready, but it still
+//     serves new RPCs.
+//  2. Deregister from service discovery.
+//  3. Wait for the grace period, so load balancers and clients notice.
+//  4. Reject new RPCs with Unavailable, and ask endless streams, e.g.
+//     Ticker, to end, so their clients resume them on other servers
+//     instead of waiting for the timeout.
+//  5. Stop the gRPC server gracefully, waiting for in-flight RPCs and
+//     streams to finish, and shut down the HTTP servers.
+//  6. Force-stop them if they didn't finish within the timeout.
+type shutdownSequence struct {
+	logger      log.Logger
+	health      *health.Registry
+	grace       time.Duration
+	timeout     time.Duration
+	grpcServer  *grpc.Server
+	httpServers []*http.Server
+
+	mu          sync.Mutex
+	deregisters []func()
+	stops       []func()
+}
+
+// newShutdownSequence creates a new shutdown sequence.
+func newShutdownSequence(logger log.Logger, registry *health.Registry, grace, timeout time.Duration) *shutdownSequence {
+	return &shutdownSequence{
+		logger:  log.With(logger, "component", "shutdown"),
+		health:  registry,
+		grace:   grace,
+		timeout: timeout,
+	}
+}
+
+// OnDeregister adds a function that removes the server from
+// service discovery.
+func (s *shutdownSequence) OnDeregister(f func()) {
+	s.mu.Lock()
+	s.deregisters = append(s.deregisters, f)
+	s.mu.Unlock()
+}
+
+// OnStop adds a function that is called right before the servers are
+// stopped, e.g. to end long-running streams.
+func (s *shutdownSequence) OnStop(f func()) {
+	s.mu.Lock()
+	s.stops = append(s.stops, f)
+	s.mu.Unlock()
+}
+
+// Deregister removes the server from service discovery.
+// It is safe to call Deregister more than once.
+func (s *shutdownSequence) Deregister() {
+	s.mu.Lock()
+	deregisters := s.deregisters
+	s.deregisters = nil
+	s.mu.Unlock()
+
+	for _, f := range deregisters {
+		f()
+	}
+}
+
+// Run executes the shutdown sequence. Closing force skips the
+// remaining grace period and timeout and stops the server immediately.
+func (s *shutdownSequence) Run(force <-chan struct{}) {
+	s.logger.Log("msg", "Draining", "grace", s.grace, "timeout", s.timeout)
+	s.health.SetDraining(true)
+	s.Deregister()
+
+	select {
+	case <-time.After(s.grace):
+	case <-force:
+	}
+
+	s.mu.Lock()
+	stops := s.stops
+	s.mu.Unlock()
+	for _, f := range stops {
+		f()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-force:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	if s.grpcServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done := make(chan struct{})
+			go func() {
+				s.grpcServer.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+				s.logger.Log("msg", "gRPC server stopped gracefully")
+			case <-ctx.Done():
+				s.logger.Log("msg", "Forcing gRPC server to stop", "err", ctx.Err())
+				s.grpcServer.Stop()
+			}
+		}()
+	}
+	for _, httpServer := range s.httpServers {
+		wg.Add(1)
+		go func(httpServer *http.Server) {
+			defer wg.Done()
+			if err := httpServer.Shutdown(ctx); err != nil {
+				s.logger.Log("msg", "Forcing HTTP server to stop", "err", err)
+				httpServer.Close()
+			} else {
+				s.logger.Log("msg", "HTTP server stopped gracefully")
+			}
+		}(httpServer)
+	}
+	wg.Wait()
+}
@@ -1,22 +1,53 @@
 package main
 
 import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
 	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/tap"
+
+	"github.com/olivere/grpc-demo/go-server/health"
 )
 
+// Metrics counts the calls seen by the TapHandler. It implements
+// prometheus.Collector.
 type Metrics struct {
-	calls uint64
+	calls         uint64
+	limiterErrors uint64
+
+	callsDesc         *prometheus.Desc
+	limiterErrorsDesc *prometheus.Desc
 }
 
 func NewMetrics() *Metrics {
-	return &Metrics{}
+	return &Metrics{
+		callsDesc: prometheus.NewDesc(
+			"grpc_demo_tap_calls_total",
+			"Number of calls seen by the tap handler.",
+			nil, nil,
+		),
+		limiterErrorsDesc: prometheus.NewDesc(
+			"grpc_demo_rate_limiter_errors_total",
+			"Number of calls let through without rate limiting because the rate limiter failed.",
+			nil, nil,
+		),
+	}
 }
 
 func (m *Metrics) Calls() uint64 {
@@ -27,46 +58,267 @@ func (m *Metrics) IncrementCalls(delta uint64) uint64 {
 	return atomic.AddUint64(&m.calls, delta)
 }
 
+func (m *Metrics) LimiterErrors() uint64 {
+	return atomic.LoadUint64(&m.limiterErrors)
+}
+
+func (m *Metrics) IncrementLimiterErrors(delta uint64) uint64 {
+	return atomic.AddUint64(&m.limiterErrors, delta)
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.callsDesc
+	ch <- m.limiterErrorsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(m.callsDesc, prometheus.CounterValue, float64(m.Calls()))
+	ch <- prometheus.MustNewConstMetric(m.limiterErrorsDesc, prometheus.CounterValue, float64(m.LimiterErrors()))
+}
+
 type TapHandler struct {
 	tap.ServerInHandle
 
+	logger  log.Logger
 	metrics *Metrics
+	check   *health.Check
+
+	auth    Authenticator
+	limiter RateLimiter
 
-	ratesMu sync.RWMutex
-	rates   map[string]*rate.Limiter
-	qps     rate.Limit
-	burst   int
+	policyMu sync.RWMutex
+	policy   *RatePolicy
+
+	limiterDown uint32 // 1 while the rate limiter fails
+	draining    uint32 // 1 once new calls are rejected
+
+	healthMu   sync.Mutex // serializes health check updates
+	limiterErr error      // error the rate limiter failed with
 }
 
-func NewTapHandler(metrics *Metrics, qps rate.Limit, burst int) *TapHandler {
+func NewTapHandler(logger log.Logger, metrics *Metrics, auth Authenticator, limiter RateLimiter, policy *RatePolicy) *TapHandler {
 	return &TapHandler{
+		logger:  log.With(logger, "component", "tap"),
 		metrics: metrics,
-		rates:   make(map[string]*rate.Limiter),
-		qps:     qps,
-		burst:   burst,
+		auth:    auth,
+		limiter: limiter,
+		policy:  policy,
+	}
+}
+
+// SetPolicy replaces the rate policy. Users keep their quotas, unless
+// their limit changed.
+func (h *TapHandler) SetPolicy(policy *RatePolicy) {
+	h.policyMu.Lock()
+	h.policy = policy
+	h.policyMu.Unlock()
+
+	h.healthMu.Lock()
+	h.updateHealthCheckLocked()
+	h.healthMu.Unlock()
+}
+
+// ReloadPolicy loads the rate policy from filename and replaces the
+// current one with it. If the file cannot be loaded or is invalid, it
+// returns an error and keeps the current policy.
+func (h *TapHandler) ReloadPolicy(filename string) error {
+	policy, err := LoadRatePolicy(filename)
+	if err != nil {
+		return err
 	}
+	h.SetPolicy(policy)
+	return nil
+}
+
+// RegisterHealthCheck registers a readiness check with the registry that
+// fails if the rate limiter is configured to reject every request by default,
+// and is degraded while the rate limiter fails and calls are not limited.
+func (h *TapHandler) RegisterHealthCheck(r *health.Registry) {
+	h.healthMu.Lock()
+	h.check = r.Register("ratelimiter", health.Readiness)
+	h.updateHealthCheckLocked()
+	h.healthMu.Unlock()
+}
+
+// updateHealthCheckLocked updates the health check from the policy and
+// the state of the rate limiter. The caller must hold h.healthMu.
+func (h *TapHandler) updateHealthCheckLocked() {
+	if h.check == nil {
+		return
+	}
+	h.policyMu.RLock()
+	limit := *h.policy.Default
+	h.policyMu.RUnlock()
+	if rate.Limit(limit.QPS) != rate.Inf && (limit.QPS <= 0 || limit.Burst <= 0) {
+		h.check.Fail(fmt.Sprintf("rate limiter rejects all requests with qps=%v and burst=%d", limit.QPS, limit.Burst))
+		return
+	}
+	if h.limiterErr != nil {
+		h.check.Degrade(fmt.Sprintf("rate limiter failed, calls are not rate limited: %v", h.limiterErr))
+		return
+	}
+	h.check.Pass()
+}
+
+// limiterFailed records that the rate limiter failed with err. Only the
+// first error of an outage is logged, to not flood the log with an error
+// per call; the metrics count all of them.
+func (h *TapHandler) limiterFailed(err error) {
+	h.metrics.IncrementLimiterErrors(1)
+	if atomic.LoadUint32(&h.limiterDown) == 1 {
+		return
+	}
+	h.healthMu.Lock()
+	defer h.healthMu.Unlock()
+	if h.limiterErr != nil {
+		return
+	}
+	h.logger.Log("msg", "Rate limiter failed; calls are not rate limited until it recovers", "err", err)
+	h.limiterErr = err
+	atomic.StoreUint32(&h.limiterDown, 1)
+	h.updateHealthCheckLocked()
+}
+
+// limiterRecovered records that the rate limiter works (again).
+func (h *TapHandler) limiterRecovered() {
+	if atomic.LoadUint32(&h.limiterDown) == 0 {
+		return
+	}
+	h.healthMu.Lock()
+	defer h.healthMu.Unlock()
+	if h.limiterErr == nil {
+		return
+	}
+	h.logger.Log("msg", "Rate limiter recovered")
+	h.limiterErr = nil
+	atomic.StoreUint32(&h.limiterDown, 0)
+	h.updateHealthCheckLocked()
+}
+
+// Drain rejects new calls with Unavailable from now on, so that clients
+// retry them on other servers. It is called when the server stops, after
+// the grace period of the shutdown; until then, the server is unready,
+// but still serves the calls that reach it.
+func (h *TapHandler) Drain() {
+	atomic.StoreUint32(&h.draining, 1)
 }
 
 func (h *TapHandler) Handle(ctx context.Context, info *tap.Info) (context.Context, error) {
 	h.metrics.IncrementCalls(1)
 
-	// Rate limiter per user
-	user, ok := extractUserFromMD(ctx)
+	// Health checks are neither authenticated nor rate limited
+	if strings.HasPrefix(info.FullMethodName, "/grpc.health.v1.Health/") {
+		return ctx, nil
+	}
+
+	// Let clients go elsewhere while we are shutting down
+	if atomic.LoadUint32(&h.draining) == 1 {
+		return nil, status.Error(codes.Unavailable, "server is shutting down")
+	}
+
+	// Authenticate here, so that we rate limit by verified users.
+	// Like with rate limiting below, we leave rejecting the call to the
+	// interceptors, which can return the proper error to the client.
+	// Calls that fail to authenticate share a quota per peer address,
+	// with the limits of users without a tier, so that tokens cannot
+	// be guessed at full speed.
+	var user, keyPrefix string
+	id, err := authenticate(ctx, h.auth)
+	if err != nil {
+		ctx = context.WithValue(ctx, authErrorKey, err)
+		keyPrefix = "anonymous@" + peerHost(ctx)
+	} else {
+		ctx = context.WithValue(ctx, identityKey, id)
+		user, keyPrefix = id.Subject, id.Subject
+	}
+
+	// Rate limiter per user. The key only depends on the policy, so that
+	// all servers with the same policy share the quota of a user, and
+	// reloading the policy only resets the quotas of limits that changed.
+	h.policyMu.RLock()
+	limit, scope := h.policy.Lookup(user, info.FullMethodName)
+	h.policyMu.RUnlock()
+	key := fmt.Sprintf("%s/%s/%g:%d", keyPrefix, scope, limit.QPS, limit.Burst)
+
+	quota, err := h.limiter.Allow(ctx, key, limit)
+	if err != nil {
+		// Don't let an outage of a shared limiter take us down as well
+		h.limiterFailed(err)
+		return ctx, nil
+	}
+	h.limiterRecovered()
+
+	// Errors returned from here only reach the client as a refused
+	// stream, so we leave rejecting the call to the interceptors,
+	// which can also tell the client when to retry.
+	return context.WithValue(ctx, quotaKey, quota), nil
+}
+
+// peerHost returns the host of the peer of the call in ctx, without
+// the port, so that all connections of a client share a quota.
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// UnaryServerInterceptor rejects calls that exceeded their quota with
+// ResourceExhausted and a RetryInfo detail. Successful calls get the
+// remaining quota in their trailers.
+func (h *TapHandler) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	quota, ok := ctx.Value(quotaKey).(Quota)
 	if !ok {
-		return nil, status.Error(codes.Unauthenticated,
-			"client didn't pass a user")
+		return handler(ctx, req)
 	}
+	grpc.SetTrailer(ctx, quotaMetadata(quota))
+	if !quota.Allowed {
+		return nil, rateLimitError(quota)
+	}
+	return handler(ctx, req)
+}
 
-	h.ratesMu.Lock()
-	if h.rates[user] == nil {
-		h.rates[user] = rate.NewLimiter(h.qps, h.burst) // QPS, burst
+// StreamServerInterceptor rejects streams that exceeded their quota with
+// ResourceExhausted and a RetryInfo detail. Successful streams get the
+// remaining quota in their trailers.
+func (h *TapHandler) StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	quota, ok := stream.Context().Value(quotaKey).(Quota)
+	if !ok {
+		return handler(srv, stream)
 	}
-	if !h.rates[user].Allow() {
-		h.ratesMu.Unlock()
-		return nil, status.Error(codes.ResourceExhausted,
-			"client exceeded rate limit")
+	stream.SetTrailer(quotaMetadata(quota))
+	if !quota.Allowed {
+		return rateLimitError(quota)
 	}
-	h.ratesMu.Unlock()
+	return handler(srv, stream)
+}
 
-	return ctx, nil
+// quotaMetadata returns the remaining quota and the number of seconds
+// until it is fully replenished, as metadata.
+func quotaMetadata(quota Quota) metadata.MD {
+	reset := int64(math.Ceil(quota.ResetAfter.Seconds()))
+	return metadata.Pairs(
+		"ratelimit-remaining", strconv.Itoa(quota.Remaining),
+		"ratelimit-reset", strconv.FormatInt(reset, 10),
+	)
+}
+
+// rateLimitError returns a ResourceExhausted error that tells the client
+// when to retry via a RetryInfo detail.
+func rateLimitError(quota Quota) error {
+	st := status.New(codes.ResourceExhausted, "client exceeded rate limit")
+	details, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(quota.RetryAfter),
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return details.Err()
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// tickerToken is the state of a Ticker stream. It is sent to clients as
+// the resume token of every tick, and passed back to resume the stream.
+// Ticks are scheduled at Start + n*Interval, where n is the sequence
+// number, so a resumed stream keeps the schedule of the original one.
+type tickerToken struct {
+	Start    int64 // start of the stream in nanoseconds since the epoch
+	Interval int64 // interval in nanoseconds
+	Sequence int64 // sequence number of the last tick sent
+}
+
+// Sign encodes t as an opaque resume token, signed with key via
+// HMAC-SHA256, so that clients cannot forge or modify tokens.
+func (t tickerToken) Sign(key []byte) string {
+	payload := []byte(fmt.Sprintf("%d:%d:%d", t.Start, t.Interval, t.Sequence))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(tickerTokenMAC(key, payload))
+}
+
+// LastTick returns the time of the last tick sent, in nanoseconds since
+// the epoch. ok is false if that overflows an int64.
+func (t tickerToken) LastTick() (nanos int64, ok bool) {
+	if t.Sequence > (math.MaxInt64-t.Start)/t.Interval {
+		return 0, false
+	}
+	return t.Start + t.Sequence*t.Interval, true
+}
+
+// tickerTokenMAC returns the signature of the token payload.
+func tickerTokenMAC(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// newTickerKey returns a random key to sign resume tokens with.
+func newTickerKey() ([]byte, error) {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+var errInvalidTickerToken = errors.New("invalid resume token")
+
+// parseTickerToken decodes a resume token created by tickerToken.Sign,
+// and checks that it has been signed with key.
+func parseTickerToken(s string, key []byte) (tickerToken, error) {
+	var t tickerToken
+	i := strings.IndexByte(s, '.')
+	if i < 0 {
+		return t, errInvalidTickerToken
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s[:i])
+	if err != nil {
+		return t, errInvalidTickerToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(s[i+1:])
+	if err != nil || !hmac.Equal(sig, tickerTokenMAC(key, b)) {
+		return t, errInvalidTickerToken
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 3 {
+		return t, errInvalidTickerToken
+	}
+	for i, p := range []*int64{&t.Start, &t.Interval, &t.Sequence} {
+		if *p, err = strconv.ParseInt(parts[i], 10, 64); err != nil {
+			return t, errInvalidTickerToken
+		}
+	}
+	if t.Start <= 0 || t.Interval <= 0 || t.Sequence < 0 {
+		return t, errInvalidTickerToken
+	}
+	if _, ok := t.LastTick(); !ok {
+		return t, errInvalidTickerToken
+	}
+	return t, nil
+}
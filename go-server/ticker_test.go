@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+var testTickerKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestTickerTokenRoundTrip(t *testing.T) {
+	token := tickerToken{Start: time.Now().UnixNano(), Interval: int64(time.Second), Sequence: 42}
+	parsed, err := parseTickerToken(token.Sign(testTickerKey), testTickerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := token, parsed; want != have {
+		t.Errorf("expected token %+v, have %+v", want, have)
+	}
+}
+
+func TestParseTickerTokenRejectsInvalidTokens(t *testing.T) {
+	token := tickerToken{Start: time.Now().UnixNano(), Interval: int64(time.Second), Sequence: 42}
+	signed := token.Sign(testTickerKey)
+	payload := signed[:strings.IndexByte(signed, '.')]
+	mac := signed[strings.IndexByte(signed, '.')+1:]
+
+	// A token with the MAC of another token
+	other := tickerToken{Start: token.Start, Interval: token.Interval, Sequence: 1000}.Sign(testTickerKey)
+	otherPayload := other[:strings.IndexByte(other, '.')]
+
+	// Change the first character of the MAC
+	flipped := "A" + mac[1:]
+	if flipped == mac {
+		flipped = "B" + mac[1:]
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"tampered MAC", payload + "." + flipped},
+		{"payload of another token", otherPayload + "." + mac},
+		{"signed with a different key", token.Sign([]byte("another-ticker-key"))},
+		{"unsigned", payload},
+		{"empty MAC", payload + "."},
+		{"overflowing sequence", tickerToken{Start: token.Start, Interval: token.Interval, Sequence: math.MaxInt64 / 2}.Sign(testTickerKey)},
+		{"garbage", "not-a-token"},
+	}
+	for _, tt := range tests {
+		if _, err := parseTickerToken(tt.token, testTickerKey); err != errInvalidTickerToken {
+			t.Errorf("%s: expected %v, have %v", tt.name, errInvalidTickerToken, err)
+		}
+	}
+}
+
+func TestValidateTickerRequestResumeToken(t *testing.T) {
+	now := time.Now()
+	interval := int64(time.Second)
+	tokenAt := func(lastTick time.Time) string {
+		start := lastTick.Add(-10 * time.Second)
+		return tickerToken{Start: start.UnixNano(), Interval: interval, Sequence: 10}.Sign(testTickerKey)
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		interval    int64
+		description string // of the violation, blank if valid
+	}{
+		{"valid", tokenAt(now.Add(-time.Minute)), interval, ""},
+		{"different key", tickerToken{Start: now.UnixNano(), Interval: interval}.Sign([]byte("another-ticker-key")), interval, "must be the resume token of a previous response"},
+		{"different interval", tokenAt(now), 2 * interval, "was issued for an interval of 1s"},
+		{"expired", tokenAt(now.Add(-maxTickerResume - time.Minute)), interval, "has expired"},
+		{"in the future", tokenAt(now.Add(time.Hour)), interval, "in the future"},
+	}
+	for _, tt := range tests {
+		err := validateTickerRequest(&pb.TickerRequest{Interval: tt.interval, ResumeToken: tt.token}, testTickerKey, now)
+		if tt.description == "" {
+			if err != nil {
+				t.Errorf("%s: expected request to be valid, have %v", tt.name, err)
+			}
+			continue
+		}
+		if have := resumeTokenViolation(err); !strings.Contains(have, tt.description) {
+			t.Errorf("%s: expected resume_token violation %q, have %q (%v)", tt.name, tt.description, have, err)
+		}
+	}
+}
+
+// resumeTokenViolation returns the description of the violation of the
+// resume_token field in err, if any.
+func resumeTokenViolation(err error) string {
+	st, _ := status.FromError(err)
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.FieldViolations {
+				if v.Field == "resume_token" {
+					return v.Description
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/olivere/grpc-demo/pb"
+)
+
+const (
+	maxNameLength     = 100
+	maxAge            = 150
+	maxTags           = 10
+	maxTagLength      = 50
+	maxProperties     = 10
+	maxClockSkew      = time.Minute
+	maxRoomLength     = 100
+	maxChatTextLength = 1000
+	minTickerInterval = 100 * time.Millisecond
+	maxTickerInterval = time.Hour
+	maxTickerResume   = 24 * time.Hour
+)
+
+// validateHelloRequest checks req, received at now. If req is invalid,
+// it returns an InvalidArgument error with a BadRequest detail that lists
+// all violations, so clients can fix all fields at once.
+func validateHelloRequest(req *pb.HelloRequest, now time.Time) error {
+	return helloRequestViolations(req, now).err("invalid request")
+}
+
+// helloRequestViolations returns the violations of req, received at now.
+func helloRequestViolations(req *pb.HelloRequest, now time.Time) fieldViolations {
+	var v fieldViolations
+
+	if strings.TrimSpace(req.Name) == "" {
+		v.add("name", "must not be blank")
+	} else if n := utf8.RuneCountInString(req.Name); n > maxNameLength {
+		v.add("name", "must have at most %d characters; has %d", maxNameLength, n)
+	}
+	if req.Age < 0 || req.Age > maxAge {
+		v.add("age", "must be 0-%d; was %d", maxAge, req.Age)
+	}
+	if req.Nanos < 0 {
+		v.add("nanos", "must not be negative; was %d", req.Nanos)
+	} else if req.Nanos > 0 && time.Unix(0, req.Nanos).Sub(now) > maxClockSkew {
+		v.add("nanos", "must not be more than %v in the future", maxClockSkew)
+	}
+	if len(req.Tags) > maxTags {
+		v.add("tags", "must have at most %d tags; has %d", maxTags, len(req.Tags))
+	}
+	seen := make(map[string]bool)
+	for i, tag := range req.Tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		switch {
+		case strings.TrimSpace(tag) == "":
+			v.add(field, "must not be blank")
+		case utf8.RuneCountInString(tag) > maxTagLength:
+			v.add(field, "must have at most %d characters", maxTagLength)
+		case seen[tag]:
+			v.add(field, "duplicate tag %q", tag)
+		}
+		seen[tag] = true
+	}
+	if len(req.Properties) > maxProperties {
+		v.add("properties", "must have at most %d properties; has %d", maxProperties, len(req.Properties))
+	}
+	if _, ok := req.Properties[""]; ok {
+		v.add("properties[]", "key must not be blank")
+	}
+	if _, ok := pb.Gender_name[int32(req.Gender)]; !ok {
+		v.add("gender", "unknown gender %d", req.Gender)
+	}
+
+	return v
+}
+
+// validateTickerRequest checks req, received at now. A resume token must
+// have been signed with key, have been issued for the same interval, and
+// its last tick must be at most maxTickerResume ago.
+func validateTickerRequest(req *pb.TickerRequest, key []byte, now time.Time) error {
+	var v fieldViolations
+	if d := time.Duration(req.Interval); d < minTickerInterval || d > maxTickerInterval {
+		v.add("interval", "must be %v-%v; was %v", minTickerInterval, maxTickerInterval, d)
+	}
+	if req.ResumeToken != "" {
+		if token, err := parseTickerToken(req.ResumeToken, key); err != nil {
+			v.add("resume_token", "must be the resume token of a previous response")
+		} else {
+			last, _ := token.LastTick() // checked by parseTickerToken
+			switch {
+			case token.Interval != req.Interval:
+				v.add("resume_token", "was issued for an interval of %v", time.Duration(token.Interval))
+			case time.Unix(0, last).Sub(now) > maxClockSkew:
+				v.add("resume_token", "must not be more than %v in the future", maxClockSkew)
+			case now.Sub(time.Unix(0, last)) > maxTickerResume:
+				v.add("resume_token", "has expired; streams can be resumed for %v", maxTickerResume)
+			}
+		}
+	}
+	if req.MaxCount < 0 {
+		v.add("max_count", "must not be negative; was %d", req.MaxCount)
+	}
+	if req.End < 0 {
+		v.add("end", "must not be negative; was %d", req.End)
+	}
+	return v.err("invalid request")
+}
+
+// validateChatMessage checks msg, sent by a client to room. room is blank
+// for the first message, which must name the room to join.
+func validateChatMessage(msg *pb.ChatMessage, room string) error {
+	var v fieldViolations
+	switch {
+	case room == "" && strings.TrimSpace(msg.Room) == "":
+		v.add("room", "must not be blank in the first message")
+	case room == "" && utf8.RuneCountInString(msg.Room) > maxRoomLength:
+		v.add("room", "must have at most %d characters", maxRoomLength)
+	case room != "" && msg.Room != "" && msg.Room != room:
+		v.add("room", "cannot change rooms; leave %q first", room)
+	}
+	if n := utf8.RuneCountInString(msg.Text); n > maxChatTextLength {
+		v.add("text", "must have at most %d characters; has %d", maxChatTextLength, n)
+	}
+	return v.err("invalid message")
+}
+
+// fieldViolations collects the violations of a request.
+type fieldViolations []*errdetails.BadRequest_FieldViolation
+
+func (v *fieldViolations) add(field, format string, args ...interface{}) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns nil if there are no violations, or an InvalidArgument error
+// with msg and the violations in a BadRequest detail otherwise.
+func (v fieldViolations) err(msg string) error {
+	if len(v) == 0 {
+		return nil
+	}
+	st := status.New(codes.InvalidArgument, msg)
+	details, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v})
+	if err != nil {
+		return st.Err()
+	}
+	return details.Err()
+}
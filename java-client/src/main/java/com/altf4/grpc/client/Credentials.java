@@ -11,12 +11,12 @@ import java.util.concurrent.Executor;
  * Created by oliver on 25.06.17.
  */
 public class Credentials implements io.grpc.CallCredentials {
-    public static final Metadata.Key<String> USER_KEY = Metadata.Key.of("user", Metadata.ASCII_STRING_MARSHALLER);
+    public static final Metadata.Key<String> AUTHORIZATION_KEY = Metadata.Key.of("authorization", Metadata.ASCII_STRING_MARSHALLER);
 
-    private final String user;
+    private final String token;
 
-    public Credentials(String user) {
-        this.user = user;
+    public Credentials(String token) {
+        this.token = token;
     }
 
     @Override
@@ -24,7 +24,7 @@ public class Credentials implements io.grpc.CallCredentials {
         executor.execute(() -> {
             try {
                 Metadata md = new Metadata();
-                md.put(USER_KEY, this.user);
+                md.put(AUTHORIZATION_KEY, "Bearer " + this.token);
                 metadataApplier.apply(md);
             } catch (Throwable e) {
                 metadataApplier.fail(Status.UNAUTHENTICATED.withCause(e));
@@ -12,7 +12,6 @@ import javax.net.ssl.SSLContext;
 import javax.net.ssl.SSLException;
 import java.io.File;
 import java.util.List;
-import java.util.UUID;
 
 /**
  * Created by oliver on 24.06.17.
@@ -28,6 +27,7 @@ public class Main {
         options.addOption("serverName", true, "Server to check the certificate");
         options.addOption("caFile", true, "Certificate file in e.g. in PEM format");
         options.addOption("interval", true, "Time interval between ticker responses");
+        options.addOption("token", true, "Bearer token to authenticate with (defaults to $TOKEN)");
         HelpFormatter helpFormatter = new HelpFormatter();
         CommandLine cmd = null;
         try {
@@ -99,7 +99,15 @@ public class Main {
             channelBuilder = channelBuilder.usePlaintext(true);
         }
 
-        Credentials creds = new Credentials(UUID.randomUUID().toString());
+        // Authentication
+        String token = System.getenv("TOKEN");
+        if (cmd.hasOption("token")) {
+            token = cmd.getOptionValue("token");
+        }
+        Credentials creds = null;
+        if (token != null && !token.isEmpty()) {
+            creds = new Credentials(token);
+        }
         ExampleClient client = new ExampleClient(channelBuilder, creds);
 
         switch (cmd.getArgList().stream().findFirst().orElse("hello")) {
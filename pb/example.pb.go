@@ -12,6 +12,8 @@ It has these top-level messages:
 	HelloResponse
 	TickerRequest
 	TickerResponse
+	HelloManyResponse
+	ChatMessage
 */
 package pb
 
@@ -128,6 +130,14 @@ func (m *HelloRequest) GetOnline() bool {
 // HelloResponse is a simple RPC response.
 type HelloResponse struct {
 	Message string `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
+	// latency is the time in nanoseconds from the client sending the request,
+	// as given in its nanos, to the server receiving it. It is 0 if the
+	// request has no nanos, and may be negative if their clocks differ.
+	Latency int64 `protobuf:"varint,2,opt,name=latency" json:"latency,omitempty"`
+	// tags, properties and online echo the request.
+	Tags       []string          `protobuf:"bytes,3,rep,name=tags" json:"tags,omitempty"`
+	Properties map[string]string `protobuf:"bytes,4,rep,name=properties" json:"properties,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Online     bool              `protobuf:"varint,5,opt,name=online" json:"online,omitempty"`
 }
 
 func (m *HelloResponse) Reset()                    { *m = HelloResponse{} }
@@ -142,10 +152,47 @@ func (m *HelloResponse) GetMessage() string {
 	return ""
 }
 
+func (m *HelloResponse) GetLatency() int64 {
+	if m != nil {
+		return m.Latency
+	}
+	return 0
+}
+
+func (m *HelloResponse) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *HelloResponse) GetProperties() map[string]string {
+	if m != nil {
+		return m.Properties
+	}
+	return nil
+}
+
+func (m *HelloResponse) GetOnline() bool {
+	if m != nil {
+		return m.Online
+	}
+	return false
+}
+
 // TickerRequest initiates the streaming Ticker API.
 type TickerRequest struct {
 	Timezone string `protobuf:"bytes,1,opt,name=timezone" json:"timezone,omitempty"`
 	Interval int64  `protobuf:"varint,2,opt,name=interval" json:"interval,omitempty"`
+	// resume_token is the resume_token of the last response received, to
+	// continue an interrupted stream after it, with the same interval.
+	ResumeToken string `protobuf:"bytes,3,opt,name=resume_token,json=resumeToken" json:"resume_token,omitempty"`
+	// max_count ends the stream after the tick with that sequence number,
+	// if not 0.
+	MaxCount int64 `protobuf:"varint,4,opt,name=max_count,json=maxCount" json:"max_count,omitempty"`
+	// end ends the stream at that time in nanoseconds since the epoch,
+	// if not 0.
+	End int64 `protobuf:"varint,5,opt,name=end" json:"end,omitempty"`
 }
 
 func (m *TickerRequest) Reset()                    { *m = TickerRequest{} }
@@ -167,10 +214,36 @@ func (m *TickerRequest) GetInterval() int64 {
 	return 0
 }
 
+func (m *TickerRequest) GetResumeToken() string {
+	if m != nil {
+		return m.ResumeToken
+	}
+	return ""
+}
+
+func (m *TickerRequest) GetMaxCount() int64 {
+	if m != nil {
+		return m.MaxCount
+	}
+	return 0
+}
+
+func (m *TickerRequest) GetEnd() int64 {
+	if m != nil {
+		return m.End
+	}
+	return 0
+}
+
 // TickerResponse is a response sent back from the server to the client,
 // in a streaming manner.
 type TickerResponse struct {
 	Tick string `protobuf:"bytes,1,opt,name=tick" json:"tick,omitempty"`
+	// sequence is the number of the tick, starting at 1. It increases
+	// with every tick; after a resume, it skips the ticks that were missed.
+	Sequence    int64  `protobuf:"varint,2,opt,name=sequence" json:"sequence,omitempty"`
+	Nanos       int64  `protobuf:"varint,3,opt,name=nanos" json:"nanos,omitempty"`
+	ResumeToken string `protobuf:"bytes,4,opt,name=resume_token,json=resumeToken" json:"resume_token,omitempty"`
 }
 
 func (m *TickerResponse) Reset()                    { *m = TickerResponse{} }
@@ -185,11 +258,95 @@ func (m *TickerResponse) GetTick() string {
 	return ""
 }
 
+func (m *TickerResponse) GetSequence() int64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *TickerResponse) GetNanos() int64 {
+	if m != nil {
+		return m.Nanos
+	}
+	return 0
+}
+
+func (m *TickerResponse) GetResumeToken() string {
+	if m != nil {
+		return m.ResumeToken
+	}
+	return ""
+}
+
+// HelloManyResponse is the response to a stream of Hello requests.
+type HelloManyResponse struct {
+	Responses []*HelloResponse `protobuf:"bytes,1,rep,name=responses" json:"responses,omitempty"`
+}
+
+func (m *HelloManyResponse) Reset()                    { *m = HelloManyResponse{} }
+func (m *HelloManyResponse) String() string            { return proto.CompactTextString(m) }
+func (*HelloManyResponse) ProtoMessage()               {}
+func (*HelloManyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *HelloManyResponse) GetResponses() []*HelloResponse {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
+// ChatMessage is a message in a chat room. Clients only need to set
+// the room in their first message; the server sets the other fields
+// of messages it sends. Messages without a user are from the server,
+// e.g. when users join or leave.
+type ChatMessage struct {
+	Room  string `protobuf:"bytes,1,opt,name=room" json:"room,omitempty"`
+	User  string `protobuf:"bytes,2,opt,name=user" json:"user,omitempty"`
+	Text  string `protobuf:"bytes,3,opt,name=text" json:"text,omitempty"`
+	Nanos int64  `protobuf:"varint,4,opt,name=nanos" json:"nanos,omitempty"`
+}
+
+func (m *ChatMessage) Reset()                    { *m = ChatMessage{} }
+func (m *ChatMessage) String() string            { return proto.CompactTextString(m) }
+func (*ChatMessage) ProtoMessage()               {}
+func (*ChatMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *ChatMessage) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
+func (m *ChatMessage) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *ChatMessage) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+func (m *ChatMessage) GetNanos() int64 {
+	if m != nil {
+		return m.Nanos
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*HelloRequest)(nil), "com.altf4.grpc.HelloRequest")
 	proto.RegisterType((*HelloResponse)(nil), "com.altf4.grpc.HelloResponse")
 	proto.RegisterType((*TickerRequest)(nil), "com.altf4.grpc.TickerRequest")
 	proto.RegisterType((*TickerResponse)(nil), "com.altf4.grpc.TickerResponse")
+	proto.RegisterType((*HelloManyResponse)(nil), "com.altf4.grpc.HelloManyResponse")
+	proto.RegisterType((*ChatMessage)(nil), "com.altf4.grpc.ChatMessage")
 	proto.RegisterEnum("com.altf4.grpc.Gender", Gender_name, Gender_value)
 }
 
@@ -209,6 +366,13 @@ type ExampleClient interface {
 	// Ticker is a streaming API, where the client initiates the stream
 	// and then receives and endless stream of responses.
 	Ticker(ctx context.Context, in *TickerRequest, opts ...grpc.CallOption) (Example_TickerClient, error)
+	// HelloMany is a client-streaming API, where the client sends a batch
+	// of Hello requests and receives a single response for all of them.
+	HelloMany(ctx context.Context, opts ...grpc.CallOption) (Example_HelloManyClient, error)
+	// Chat is a bidirectional streaming API. The first message of the
+	// client joins a room; after that, the client sends messages to the
+	// room and receives the messages of all users in the room.
+	Chat(ctx context.Context, opts ...grpc.CallOption) (Example_ChatClient, error)
 }
 
 type exampleClient struct {
@@ -260,6 +424,71 @@ func (x *exampleTickerClient) Recv() (*TickerResponse, error) {
 	return m, nil
 }
 
+func (c *exampleClient) HelloMany(ctx context.Context, opts ...grpc.CallOption) (Example_HelloManyClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Example_serviceDesc.Streams[1], c.cc, "/com.altf4.grpc.Example/HelloMany", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exampleHelloManyClient{stream}
+	return x, nil
+}
+
+type Example_HelloManyClient interface {
+	Send(*HelloRequest) error
+	CloseAndRecv() (*HelloManyResponse, error)
+	grpc.ClientStream
+}
+
+type exampleHelloManyClient struct {
+	grpc.ClientStream
+}
+
+func (x *exampleHelloManyClient) Send(m *HelloRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *exampleHelloManyClient) CloseAndRecv() (*HelloManyResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(HelloManyResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *exampleClient) Chat(ctx context.Context, opts ...grpc.CallOption) (Example_ChatClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Example_serviceDesc.Streams[2], c.cc, "/com.altf4.grpc.Example/Chat", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exampleChatClient{stream}
+	return x, nil
+}
+
+type Example_ChatClient interface {
+	Send(*ChatMessage) error
+	Recv() (*ChatMessage, error)
+	grpc.ClientStream
+}
+
+type exampleChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *exampleChatClient) Send(m *ChatMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *exampleChatClient) Recv() (*ChatMessage, error) {
+	m := new(ChatMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for Example service
 
 type ExampleServer interface {
@@ -268,6 +497,13 @@ type ExampleServer interface {
 	// Ticker is a streaming API, where the client initiates the stream
 	// and then receives and endless stream of responses.
 	Ticker(*TickerRequest, Example_TickerServer) error
+	// HelloMany is a client-streaming API, where the client sends a batch
+	// of Hello requests and receives a single response for all of them.
+	HelloMany(Example_HelloManyServer) error
+	// Chat is a bidirectional streaming API. The first message of the
+	// client joins a room; after that, the client sends messages to the
+	// room and receives the messages of all users in the room.
+	Chat(Example_ChatServer) error
 }
 
 func RegisterExampleServer(s *grpc.Server, srv ExampleServer) {
@@ -313,6 +549,58 @@ func (x *exampleTickerServer) Send(m *TickerResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Example_HelloMany_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ExampleServer).HelloMany(&exampleHelloManyServer{stream})
+}
+
+type Example_HelloManyServer interface {
+	SendAndClose(*HelloManyResponse) error
+	Recv() (*HelloRequest, error)
+	grpc.ServerStream
+}
+
+type exampleHelloManyServer struct {
+	grpc.ServerStream
+}
+
+func (x *exampleHelloManyServer) SendAndClose(m *HelloManyResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *exampleHelloManyServer) Recv() (*HelloRequest, error) {
+	m := new(HelloRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Example_Chat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ExampleServer).Chat(&exampleChatServer{stream})
+}
+
+type Example_ChatServer interface {
+	Send(*ChatMessage) error
+	Recv() (*ChatMessage, error)
+	grpc.ServerStream
+}
+
+type exampleChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *exampleChatServer) Send(m *ChatMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *exampleChatServer) Recv() (*ChatMessage, error) {
+	m := new(ChatMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Example_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "com.altf4.grpc.Example",
 	HandlerType: (*ExampleServer)(nil),
@@ -328,6 +616,17 @@ var _Example_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Example_Ticker_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "HelloMany",
+			Handler:       _Example_HelloMany_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Chat",
+			Handler:       _Example_Chat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "example.proto",
 }
@@ -335,32 +634,44 @@ var _Example_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("example.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 422 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x52, 0x4d, 0x6f, 0xd3, 0x40,
-	0x10, 0xed, 0xda, 0x89, 0x93, 0x4e, 0x49, 0x1a, 0x8d, 0x50, 0x64, 0x45, 0x80, 0x2c, 0x8b, 0x83,
-	0x41, 0xc8, 0xa0, 0xc0, 0x01, 0x21, 0x71, 0xe0, 0xc3, 0x29, 0x15, 0x05, 0x45, 0x0b, 0x5c, 0xb8,
-	0xb9, 0x61, 0x88, 0xac, 0xd8, 0xbb, 0x66, 0x77, 0x5b, 0x51, 0x7e, 0x0c, 0x7f, 0x82, 0x3f, 0x88,
-	0xd6, 0x6b, 0x47, 0x6d, 0x50, 0xb8, 0xbd, 0xa7, 0x99, 0x9d, 0x79, 0xef, 0xed, 0xc0, 0x88, 0x7e,
-	0xe6, 0x55, 0x5d, 0x52, 0x5a, 0x2b, 0x69, 0x24, 0x8e, 0x57, 0xb2, 0x4a, 0xf3, 0xd2, 0x7c, 0x7f,
-	0x96, 0xae, 0x55, 0xbd, 0x8a, 0xff, 0x78, 0x70, 0xeb, 0x1d, 0x95, 0xa5, 0xe4, 0xf4, 0xe3, 0x82,
-	0xb4, 0x41, 0x84, 0x9e, 0xc8, 0x2b, 0x0a, 0x59, 0xc4, 0x92, 0x43, 0xde, 0x60, 0x9c, 0x80, 0x9f,
-	0xaf, 0x29, 0xf4, 0x22, 0x96, 0xf4, 0xb9, 0x85, 0x78, 0x1b, 0xfa, 0x22, 0x17, 0x52, 0x87, 0x7e,
-	0xc4, 0x12, 0x9f, 0x3b, 0x62, 0xdf, 0x9a, 0x7c, 0xad, 0xc3, 0x5e, 0xe4, 0xdb, 0xb7, 0x16, 0xe3,
-	0x19, 0x40, 0xad, 0x64, 0x4d, 0xca, 0x14, 0xa4, 0xc3, 0x7e, 0xe4, 0x27, 0x47, 0xf3, 0x47, 0xe9,
-	0x4d, 0x15, 0xe9, 0x75, 0x05, 0xe9, 0x72, 0xdb, 0x9e, 0x09, 0xa3, 0xae, 0xf8, 0xb5, 0xf7, 0x98,
-	0x42, 0xb0, 0x26, 0xf1, 0x8d, 0x54, 0x18, 0x44, 0x2c, 0x19, 0xcf, 0xa7, 0xbb, 0x93, 0x4e, 0x9a,
-	0x2a, 0x6f, 0xbb, 0x70, 0x0a, 0x81, 0x14, 0x65, 0x21, 0x28, 0x1c, 0x44, 0x2c, 0x19, 0xf2, 0x96,
-	0xcd, 0x5e, 0xc2, 0xf1, 0xce, 0x1a, 0x6b, 0x72, 0x43, 0x57, 0xad, 0x6f, 0x0b, 0xad, 0xc9, 0xcb,
-	0xbc, 0xbc, 0x70, 0xc6, 0x0f, 0xb9, 0x23, 0x2f, 0xbc, 0xe7, 0x2c, 0x7e, 0x00, 0xa3, 0x56, 0xb2,
-	0xae, 0xa5, 0xd0, 0x84, 0x21, 0x0c, 0x2a, 0xd2, 0xda, 0xa6, 0xe4, 0x06, 0x74, 0x34, 0x3e, 0x81,
-	0xd1, 0xe7, 0x62, 0xb5, 0x21, 0xd5, 0x05, 0x3c, 0x83, 0xa1, 0x29, 0x2a, 0xfa, 0x25, 0x45, 0xd7,
-	0xbb, 0xe5, 0xb6, 0x56, 0x08, 0x43, 0xea, 0x32, 0x2f, 0x9b, 0xa5, 0x3e, 0xdf, 0xf2, 0xf8, 0x3e,
-	0x8c, 0xbb, 0x41, 0xed, 0x52, 0x1b, 0x77, 0xb1, 0xda, 0x74, 0x5f, 0x65, 0xf1, 0xc3, 0xc7, 0x10,
-	0xb8, 0x08, 0xf0, 0x18, 0x8e, 0xbe, 0x7c, 0xfc, 0xb4, 0xcc, 0xde, 0x9c, 0x2e, 0x4e, 0xb3, 0xb7,
-	0x93, 0x03, 0x1c, 0x42, 0xef, 0xc3, 0xab, 0xb3, 0x6c, 0xc2, 0x10, 0x20, 0x58, 0x64, 0x0d, 0xf6,
-	0xe6, 0xbf, 0x19, 0x0c, 0x32, 0x77, 0x22, 0xb8, 0x80, 0x7e, 0x63, 0x0b, 0xef, 0xfc, 0xef, 0x83,
-	0x66, 0x77, 0xf7, 0x54, 0x9d, 0xac, 0xf8, 0x00, 0xdf, 0x43, 0xe0, 0xa4, 0xe2, 0x3f, 0xad, 0x37,
-	0xb2, 0x98, 0xdd, 0xdb, 0x57, 0xee, 0x46, 0x3d, 0x61, 0xaf, 0xa7, 0xb0, 0x73, 0xb3, 0x4b, 0xf6,
-	0xd5, 0xab, 0xcf, 0xcf, 0x83, 0xe6, 0xa0, 0x9f, 0xfe, 0x0d, 0x00, 0x00, 0xff, 0xff, 0x74, 0xa5,
-	0xd8, 0xe0, 0xe1, 0x02, 0x00, 0x00,
+	// 623 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0xcb, 0x6e, 0xd3, 0x4c,
+	0x14, 0xee, 0xc4, 0x8e, 0xdb, 0x9c, 0xf4, 0xf6, 0x8f, 0x7e, 0x55, 0x56, 0x0a, 0xc8, 0xf5, 0xca,
+	0x42, 0x60, 0xaa, 0xc2, 0x02, 0x81, 0x58, 0x40, 0x49, 0xa0, 0xa2, 0x41, 0x91, 0x29, 0x1b, 0x36,
+	0xd5, 0xd4, 0x3d, 0x84, 0x28, 0xf6, 0x8c, 0xf1, 0x4c, 0xaa, 0x84, 0x37, 0xe1, 0x39, 0xd8, 0xf0,
+	0x72, 0x48, 0x68, 0xc6, 0x76, 0xea, 0xa6, 0x24, 0x6c, 0xd8, 0x7d, 0xc7, 0x67, 0x2e, 0xdf, 0xe5,
+	0x78, 0x60, 0x0b, 0xa7, 0x2c, 0xcd, 0x12, 0x0c, 0xb3, 0x5c, 0x28, 0x41, 0xb7, 0x63, 0x91, 0x86,
+	0x2c, 0x51, 0x9f, 0x9f, 0x84, 0xc3, 0x3c, 0x8b, 0xfd, 0x1f, 0x0d, 0xd8, 0x7c, 0x8b, 0x49, 0x22,
+	0x22, 0xfc, 0x3a, 0x41, 0xa9, 0x28, 0x05, 0x9b, 0xb3, 0x14, 0x5d, 0xe2, 0x91, 0xa0, 0x15, 0x19,
+	0x4c, 0x77, 0xc1, 0x62, 0x43, 0x74, 0x1b, 0x1e, 0x09, 0x9a, 0x91, 0x86, 0xf4, 0x7f, 0x68, 0x72,
+	0xc6, 0x85, 0x74, 0x2d, 0x8f, 0x04, 0x56, 0x54, 0x14, 0x7a, 0xaf, 0x62, 0x43, 0xe9, 0xda, 0x9e,
+	0xa5, 0xf7, 0x6a, 0x4c, 0x4f, 0x01, 0xb2, 0x5c, 0x64, 0x98, 0xab, 0x11, 0x4a, 0xb7, 0xe9, 0x59,
+	0x41, 0xfb, 0xe8, 0x41, 0x78, 0x93, 0x45, 0x58, 0x67, 0x10, 0x0e, 0xe6, 0xcb, 0xbb, 0x5c, 0xe5,
+	0xb3, 0xa8, 0xb6, 0x9f, 0x86, 0xe0, 0x0c, 0x91, 0x5f, 0x62, 0xee, 0x3a, 0x1e, 0x09, 0xb6, 0x8f,
+	0xf6, 0x16, 0x4f, 0x7a, 0x63, 0xba, 0x51, 0xb9, 0x8a, 0xee, 0x81, 0x23, 0x78, 0x32, 0xe2, 0xe8,
+	0xae, 0x7b, 0x24, 0xd8, 0x88, 0xca, 0xaa, 0xf3, 0x02, 0x76, 0x16, 0xae, 0xd1, 0x22, 0xc7, 0x38,
+	0x2b, 0x75, 0x6b, 0xa8, 0x45, 0x5e, 0xb1, 0x64, 0x52, 0x08, 0x6f, 0x45, 0x45, 0xf1, 0xac, 0xf1,
+	0x94, 0xf8, 0xbf, 0x08, 0x6c, 0x95, 0x9c, 0x65, 0x26, 0xb8, 0x44, 0xea, 0xc2, 0x7a, 0x8a, 0x52,
+	0x6a, 0x9b, 0x8a, 0x13, 0xaa, 0x52, 0x77, 0x12, 0xa6, 0x90, 0xc7, 0x33, 0x73, 0x8e, 0x15, 0x55,
+	0xe5, 0xdc, 0x2e, 0xab, 0x66, 0x57, 0xff, 0x86, 0x5d, 0xb6, 0xb1, 0xeb, 0xe1, 0x12, 0xbb, 0x8a,
+	0xab, 0x57, 0xfa, 0x75, 0xad, 0xbf, 0xf9, 0x2f, 0xf5, 0x7f, 0x27, 0xb0, 0x75, 0x36, 0x8a, 0xc7,
+	0x98, 0x57, 0x63, 0xd3, 0x81, 0x0d, 0x35, 0x4a, 0xf1, 0x9b, 0xe0, 0x95, 0x01, 0xf3, 0x5a, 0xf7,
+	0x46, 0x5c, 0x61, 0x7e, 0xc5, 0x92, 0xd2, 0x82, 0x79, 0x4d, 0x0f, 0x60, 0x33, 0x47, 0x39, 0x49,
+	0xf1, 0x5c, 0x89, 0x31, 0x72, 0x33, 0x4f, 0xad, 0xa8, 0x5d, 0x7c, 0x3b, 0xd3, 0x9f, 0xe8, 0x3e,
+	0xb4, 0x52, 0x36, 0x3d, 0x8f, 0xc5, 0x84, 0x2b, 0xd7, 0x2e, 0xf6, 0xa7, 0x6c, 0x7a, 0xac, 0x6b,
+	0xcd, 0x1a, 0xf9, 0xa5, 0x51, 0x67, 0x45, 0x1a, 0xfa, 0x33, 0xd8, 0xae, 0xa8, 0x95, 0xd9, 0x68,
+	0x9f, 0x47, 0xf1, 0xb8, 0x1a, 0x69, 0x8d, 0x35, 0x27, 0xa9, 0xa9, 0xf3, 0x18, 0x2b, 0x4e, 0x55,
+	0xbd, 0x64, 0xb8, 0x17, 0x99, 0xda, 0xb7, 0x98, 0xfa, 0x03, 0xf8, 0xcf, 0x44, 0xd3, 0x67, 0x7c,
+	0x36, 0xbf, 0xfd, 0x39, 0xb4, 0xf2, 0x12, 0x4b, 0x97, 0x98, 0x40, 0xef, 0xae, 0x0c, 0x34, 0xba,
+	0x5e, 0xef, 0x9f, 0x43, 0xfb, 0xf8, 0x0b, 0x53, 0xfd, 0x72, 0x96, 0x28, 0xd8, 0xb9, 0x10, 0x69,
+	0xa5, 0x44, 0x63, 0xfd, 0x6d, 0x22, 0x31, 0x2f, 0x43, 0x32, 0xd8, 0x28, 0xc6, 0xa9, 0x2a, 0xdd,
+	0x34, 0xf8, 0x5a, 0x95, 0x5d, 0x53, 0x75, 0xff, 0x11, 0x38, 0xc5, 0x2f, 0x43, 0x77, 0xa0, 0xfd,
+	0xf1, 0xfd, 0x87, 0x41, 0xf7, 0xf8, 0xa4, 0x77, 0xd2, 0x7d, 0xbd, 0xbb, 0x46, 0x37, 0xc0, 0xee,
+	0xbf, 0x3c, 0xed, 0xee, 0x12, 0x0a, 0xe0, 0xf4, 0xba, 0x06, 0x37, 0x8e, 0x7e, 0x36, 0x60, 0xbd,
+	0x5b, 0x3c, 0x29, 0xb4, 0x07, 0x4d, 0xc3, 0x9c, 0xde, 0x59, 0xf5, 0x43, 0x77, 0x56, 0xcb, 0xf5,
+	0xd7, 0xe8, 0x3b, 0x70, 0x8a, 0xc8, 0xe8, 0xad, 0xa5, 0x37, 0xa6, 0xac, 0x73, 0x6f, 0x59, 0xbb,
+	0x3a, 0xea, 0x90, 0xd0, 0x01, 0xb4, 0xe6, 0x21, 0xfc, 0x85, 0xd8, 0xc1, 0x1f, 0xbb, 0xf5, 0xf4,
+	0xfc, 0xb5, 0x80, 0xd0, 0x1e, 0xd8, 0x3a, 0x04, 0xba, 0xbf, 0xb8, 0xbc, 0x16, 0x4d, 0x67, 0x55,
+	0x53, 0x9f, 0x72, 0x48, 0x5e, 0xed, 0xc1, 0xc2, 0xeb, 0x3b, 0x20, 0x9f, 0x1a, 0xd9, 0xc5, 0x85,
+	0x63, 0x9e, 0xe6, 0xc7, 0xbf, 0x07, 0x00, 0x3c, 0x54, 0xf6, 0x0e, 0xab, 0x05, 0x00, 0x00,
 }